@@ -0,0 +1,312 @@
+package cue
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// TOC entries control field bits.
+	controlPre  = 0x01
+	controlDcp  = 0x02
+	controlData = 0x04
+	control4ch  = 0x08
+
+	// TOC entries points of the first track, last track and lead-out.
+	pointFirstTrack = 0xa0
+	pointLastTrack  = 0xa1
+	pointLeadOut    = 0xa2
+
+	// Disc type stored in the first track entry of CD-ROM XA discs.
+	discTypeXA = 0x20
+)
+
+// iniSection is the INI file section with upper-cased keys.
+type iniSection map[string]string
+
+// parseINI parses INI file into sections indexed by upper-cased names.
+func parseINI(r io.Reader) (map[string]iniSection, error) {
+	sections := make(map[string]iniSection)
+	var current iniSection
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line[0] == ';':
+		case line[0] == '[' && line[len(line)-1] == ']':
+			current = make(iniSection)
+			sections[strings.ToUpper(strings.TrimSpace(line[1:len(line)-1]))] = current
+		default:
+			i := strings.IndexByte(line, '=')
+			if i < 0 || current == nil {
+				return nil, fmt.Errorf("line %d: unexpected '%s'", n, line)
+			}
+			current[strings.ToUpper(strings.TrimSpace(line[:i]))] = strings.TrimSpace(line[i+1:])
+		}
+	}
+
+	return sections, scanner.Err()
+}
+
+// int returns integer (decimal or hexadecimal with 0x prefix) value of the key.
+func (s iniSection) int(key string) (int, error) {
+	v, ok := s[key]
+	if !ok {
+		return 0, fmt.Errorf("%s key is missing", key)
+	}
+	i, err := strconv.ParseInt(v, 0, 32)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to parse %s key", key)
+	}
+	return int(i), nil
+}
+
+// ccdEntry is the CloneCD TOC entry.
+type ccdEntry struct {
+	point   int
+	control int
+	pmin    int
+	psec    int
+	pframe  int
+	plba    int
+}
+
+// ParseCCD parses CloneCD control file and returns filled Sheet struct with
+// the single BINARY file of the given name. Modes of the data tracks are
+// taken from the TRACK sections, indexes positions from the INDEX keys or
+// from the TOC entries PLBA. Multi-session images are not supported.
+func ParseCCD(reader io.Reader, image string) (*Sheet, error) {
+	sections, err := parseINI(reader)
+	if err != nil {
+		return nil, err
+	}
+	disc, ok := sections["DISC"]
+	if !ok {
+		return nil, errors.New("Disc section is missing")
+	}
+	if sessions, err := disc.int("SESSIONS"); err != nil {
+		return nil, err
+	} else if sessions != 1 {
+		return nil, fmt.Errorf("%d sessions found, multi-session images are not supported", sessions)
+	}
+	count, err := disc.int("TOCENTRIES")
+	if err != nil {
+		return nil, err
+	}
+
+	var tracks []ccdEntry
+	leadOut := -1
+	for i := 0; i < count; i++ {
+		s, ok := sections[fmt.Sprintf("ENTRY %d", i)]
+		if !ok {
+			return nil, fmt.Errorf("Entry %d section is missing", i)
+		}
+		var e ccdEntry
+		for _, f := range []struct {
+			key string
+			v   *int
+		}{
+			{"POINT", &e.point}, {"CONTROL", &e.control},
+			{"PMIN", &e.pmin}, {"PSEC", &e.psec}, {"PFRAME", &e.pframe},
+		} {
+			if *f.v, err = s.int(f.key); err != nil {
+				return nil, errors.Wrapf(err, "entry %d", i)
+			}
+		}
+		if e.plba, err = s.int("PLBA"); err != nil {
+			e.plba = (e.pmin*60+e.psec)*framesPerSecond + e.pframe - msfOffset
+		}
+		switch {
+		case e.point == pointLeadOut:
+			leadOut = e.plba
+		case e.point >= 1 && e.point <= 99:
+			tracks = append(tracks, e)
+		}
+	}
+	if len(tracks) == 0 {
+		return nil, errors.New("CCD has no tracks entries")
+	}
+	sort.Slice(tracks, func(i, j int) bool { return tracks[i].point < tracks[j].point })
+
+	sheet := new(Sheet)
+	sheet.Catalog = disc["CATALOG"]
+	file := &File{Name: image, Type: FileTypeBinary}
+	sheet.Files = []*File{file}
+	if leadOut > 0 {
+		file.Duration = float64(leadOut) / framesPerSecond
+	}
+
+	for _, e := range tracks {
+		t := &Track{Number: e.point}
+		if e.control&controlDcp != 0 {
+			t.Flags = append(t.Flags, TrackFlagDcp)
+		}
+		if e.control&controlPre != 0 {
+			t.Flags = append(t.Flags, TrackFlagPre)
+		}
+		if e.control&control4ch != 0 {
+			t.Flags = append(t.Flags, TrackFlag4ch)
+		}
+
+		s, ok := sections[fmt.Sprintf("TRACK %d", e.point)]
+		mode := 0
+		if e.control&controlData != 0 {
+			mode = 1
+			if ok {
+				if mode, err = s.int("MODE"); err != nil {
+					return nil, errors.Wrapf(err, "track %d", e.point)
+				}
+			}
+		}
+		switch mode {
+		case 0:
+			t.DataType = DataTypeAudio
+		case 1:
+			t.DataType = DataTypeMode1_2352
+		case 2:
+			t.DataType = DataTypeMode2_2352
+		default:
+			return nil, fmt.Errorf("track %d has unknown mode %d", e.point, mode)
+		}
+
+		for n := 0; n <= 99 && ok; n++ {
+			if _, exists := s[fmt.Sprintf("INDEX %d", n)]; !exists {
+				continue
+			}
+			lba, err := s.int(fmt.Sprintf("INDEX %d", n))
+			if err != nil {
+				return nil, errors.Wrapf(err, "track %d", e.point)
+			}
+			t.Indexes = append(t.Indexes, Index{Number: n, Time: TimeFromFrames(lba)})
+		}
+		if len(t.Indexes) == 0 {
+			t.Indexes = []Index{{Number: 1, Time: TimeFromFrames(e.plba)}}
+		}
+		file.Tracks = append(file.Tracks, t)
+	}
+	setPositions(sheet)
+
+	return sheet, nil
+}
+
+// WriteCCD writes CloneCD control file of the single-file sheet. The size
+// of the image in bytes gives the lead-out position, all the tracks should
+// have 2352 bytes sectors.
+func WriteCCD(w io.Writer, sheet *Sheet, size int64) error {
+	if len(sheet.Files) != 1 || !isRawFile(sheet.Files[0]) {
+		return errors.New("single BINARY file sheet expected")
+	}
+	tracks := sheet.Files[0].Tracks
+	if len(tracks) == 0 {
+		return errors.New("sheet has no tracks")
+	}
+
+	type ccdTrack struct {
+		number  int
+		control int
+		mode    int
+		start   int
+		indexes []Index
+	}
+	var entries []ccdTrack
+	discType := 0
+	for _, t := range tracks {
+		if t.DataType.SectorSize() != rawSectorSize {
+			return fmt.Errorf("track %d: %s datatype is not supported by CCD", t.Number, t.DataType)
+		}
+		if t.Pregap.TotalFrames() != 0 || t.Postgap.TotalFrames() != 0 {
+			return fmt.Errorf("track %d: PREGAP and POSTGAP can't be stored in CCD", t.Number)
+		}
+		if len(t.Indexes) == 0 {
+			return fmt.Errorf("track %d has no indexes", t.Number)
+		}
+
+		e := ccdTrack{number: t.Number, start: t.StartTime().TotalFrames(), indexes: t.Indexes}
+		switch t.DataType {
+		case DataTypeMode1_2352:
+			e.control, e.mode = controlData, 1
+		case DataTypeMode2_2352, DataTypeCdi_2352:
+			e.control, e.mode = controlData, 2
+			discType = discTypeXA
+		}
+		for _, f := range t.Flags {
+			switch f {
+			case TrackFlagDcp:
+				e.control |= controlDcp
+			case TrackFlagPre:
+				e.control |= controlPre
+			case TrackFlag4ch:
+				e.control |= control4ch
+			}
+		}
+		entries = append(entries, e)
+	}
+
+	var buf bytes.Buffer
+	line := func(format string, args ...interface{}) {
+		fmt.Fprintf(&buf, format+"\r\n", args...)
+	}
+	entry := func(n, point, control, plba int) {
+		line("[Entry %d]", n)
+		line("Session=1")
+		line("Point=0x%02x", point)
+		line("ADR=0x01")
+		line("Control=0x%02x", control)
+		line("TrackNo=0")
+		line("AMin=0")
+		line("ASec=0")
+		line("AFrame=0")
+		line("ALBA=-%d", msfOffset)
+		line("Zero=0")
+		t := TimeFromFrames(plba + msfOffset)
+		line("PMin=%d", t.Min)
+		line("PSec=%d", t.Sec)
+		line("PFrame=%d", t.Frames)
+		line("PLBA=%d", plba)
+	}
+	// PLBA of the A0 and A1 entries which PMSF contains track numbers.
+	pointLBA := func(min, sec int) int {
+		return (min*60+sec)*framesPerSecond - msfOffset
+	}
+
+	first, last := entries[0], entries[len(entries)-1]
+	line("[CloneCD]")
+	line("Version=3")
+	line("[Disc]")
+	line("TocEntries=%d", len(entries)+3)
+	line("Sessions=1")
+	line("DataTracksScrambled=0")
+	line("CDTextLength=0")
+	if sheet.Catalog != "" {
+		line("CATALOG=%s", sheet.Catalog)
+	}
+	line("[Session 1]")
+	line("PreGapMode=%d", first.mode)
+	line("PreGapSubC=0")
+
+	entry(0, pointFirstTrack, first.control, pointLBA(first.number, discType))
+	entry(1, pointLastTrack, last.control, pointLBA(last.number, 0))
+	entry(2, pointLeadOut, last.control, int(size/rawSectorSize))
+	for i, e := range entries {
+		entry(i+3, e.number, e.control, e.start)
+	}
+
+	for _, e := range entries {
+		line("[TRACK %d]", e.number)
+		line("MODE=%d", e.mode)
+		for _, idx := range e.indexes {
+			line("INDEX %d=%d", idx.Number, idx.Time.TotalFrames())
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,101 @@
+package cue
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCCD(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(`CATALOG 4006381333931
+FILE "image.img" BINARY
+  TRACK 01 MODE1/2352
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    FLAGS DCP PRE
+    INDEX 00 01:00:00
+    INDEX 01 01:02:00
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	size := int64(3*60*framesPerSecond) * rawSectorSize
+	var buf bytes.Buffer
+	if err := WriteCCD(&buf, sheet, size); err != nil {
+		t.Fatalf("Failed to write CCD. %s", err.Error())
+	}
+	for _, s := range []string{
+		"[Disc]\r\nTocEntries=5\r\nSessions=1\r\n",
+		"Point=0xa0\r\nADR=0x01\r\nControl=0x04\r\n",
+		"Point=0xa2\r\nADR=0x01\r\nControl=0x03\r\n",
+		"PMin=3\r\nPSec=2\r\nPFrame=0\r\nPLBA=13500\r\n",
+		"Point=0x02\r\nADR=0x01\r\nControl=0x03\r\n",
+		"[TRACK 2]\r\nMODE=0\r\nINDEX 0=4500\r\nINDEX 1=4650\r\n",
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Fatalf("expected %q in CCD but received\n%s", s, buf.String())
+		}
+	}
+
+	parsed, err := ParseCCD(&buf, "image.img")
+	if err != nil {
+		t.Fatalf("Failed to parse CCD. %s", err.Error())
+	}
+	if d := parsed.Files[0].Duration; d != 180 {
+		t.Fatalf("expected 180 seconds duration but %f received", d)
+	}
+	var a, b bytes.Buffer
+	Write(&a, sheet)
+	Write(&b, parsed)
+	if a.String() != b.String() {
+		t.Fatalf("expected\n%s\nbut\n%s\nreceived", a.String(), b.String())
+	}
+
+	sheet.Files[0].Tracks[0].DataType = DataTypeMode1_2048
+	if err := WriteCCD(&buf, sheet, size); err == nil {
+		t.Fatalf("expected error for MODE1/2048 track")
+	}
+}
+
+func TestParseCCDEntries(t *testing.T) {
+	sheet, err := ParseCCD(strings.NewReader(`[CloneCD]
+Version=3
+[Disc]
+TocEntries=2
+Sessions=1
+[Entry 0]
+Point=0x02
+Control=0x06
+PMin=0
+PSec=32
+PFrame=0
+[Entry 1]
+Point=0x01
+Control=0x04
+PMin=0
+PSec=2
+PFrame=0
+PLBA=0
+[TRACK 2]
+MODE=2
+`), "disc.img")
+	if err != nil {
+		t.Fatalf("Failed to parse CCD. %s", err.Error())
+	}
+
+	tracks := sheet.Files[0].Tracks
+	if len(tracks) != 2 || tracks[0].DataType != DataTypeMode1_2352 || tracks[1].DataType != DataTypeMode2_2352 {
+		t.Fatalf("unexpected tracks %+v", tracks)
+	}
+	if idx := tracks[1].Indexes; len(idx) != 1 || idx[0].Time != (Time{0, 30, 0}) {
+		t.Fatalf("expected INDEX 01 00:30:00 but %v received", idx)
+	}
+	if f := tracks[1].Flags; len(f) != 1 || f[0] != TrackFlagDcp {
+		t.Fatalf("expected DCP flag but %v received", f)
+	}
+
+	if _, err := ParseCCD(strings.NewReader("[Disc]\nTocEntries=0\nSessions=2\n"), "disc.img"); err == nil {
+		t.Fatalf("expected error for multi-session image")
+	}
+}
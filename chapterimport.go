@@ -0,0 +1,404 @@
+package cue
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Rounding is the policy of the chapters times conversion into CD frames.
+type Rounding int
+
+const (
+	// Round to the nearest frame.
+	RoundNearest Rounding = iota
+	// Round to the previous frame.
+	RoundDown
+	// Round to the next frame.
+	RoundUp
+)
+
+// ImportOptions describes the sheet built from the imported chapters.
+type ImportOptions struct {
+	// Name and type of the sheet file.
+	File     string
+	FileType FileType
+	// Rounding of the chapters times into frames.
+	Rounding Rounding
+}
+
+// importedChapter is the chapter read from the chapters file.
+type importedChapter struct {
+	start time.Duration
+	// Chapter end, negative if unknown.
+	end time.Duration
+	// Position of the chapter gap (INDEX 00), zero if there is no gap.
+	gap       time.Duration
+	title     string
+	performer string
+}
+
+// frames converts duration into CD frames using the rounding policy.
+func (r Rounding) frames(d time.Duration) (int, error) {
+	if d < 0 {
+		return 0, fmt.Errorf("negative time %s", d)
+	}
+	n := int64(d) * framesPerSecond
+	frames := n / int64(time.Second)
+	switch rem := n % int64(time.Second); {
+	case rem == 0:
+	case r == RoundUp, r == RoundNearest && rem*2 >= int64(time.Second):
+		frames++
+	case r != RoundDown && r != RoundNearest:
+		return 0, fmt.Errorf("unknown rounding: %d", r)
+	}
+	return int(frames), nil
+}
+
+// sheetFromChapters builds single-file sheet with one track per chapter.
+// Returns rounding errors of the tracks INDEX 01 times (rounded time
+// minus chapter start). The region before the first chapter becomes
+// INDEX 00 of the first track, gaps of the other chapters become INDEX 00
+// of their tracks.
+func sheetFromChapters(chapters []importedChapter, opts ImportOptions) (*Sheet, []time.Duration, error) {
+	if len(chapters) == 0 {
+		return nil, nil, errors.New("no chapters found")
+	}
+	if len(chapters) > 99 {
+		return nil, nil, fmt.Errorf("%d chapters found, at most 99 tracks allowed", len(chapters))
+	}
+	sort.SliceStable(chapters, func(i, j int) bool { return chapters[i].start < chapters[j].start })
+
+	sheet := new(Sheet)
+	file := &File{Name: opts.File, Type: opts.FileType}
+	sheet.Files = []*File{file}
+	roundingErrors := make([]time.Duration, len(chapters))
+
+	last := -1
+	for i, c := range chapters {
+		frames, err := opts.Rounding.frames(c.start)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "chapter %d", i+1)
+		}
+		if frames <= last {
+			return nil, nil, fmt.Errorf("chapter %d starts at the same frame as the previous one", i+1)
+		}
+		prev := last
+		last = frames
+
+		start := TimeFromFrames(frames)
+		roundingErrors[i] = time.Duration(start.nanoseconds()) - c.start
+
+		t := &Track{Number: i + 1, DataType: DataTypeAudio, Title: c.title, Performer: c.performer}
+		switch {
+		case i == 0 && frames > 0:
+			t.Indexes = append(t.Indexes, Index{Number: 0})
+		case i > 0 && c.gap > 0:
+			gap, err := opts.Rounding.frames(c.gap)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "chapter %d gap", i+1)
+			}
+			if gap <= prev || gap >= frames {
+				return nil, nil, fmt.Errorf("chapter %d gap is out of the previous chapter", i+1)
+			}
+			t.Indexes = append(t.Indexes, Index{Number: 0, Time: TimeFromFrames(gap)})
+		}
+		t.Indexes = append(t.Indexes, Index{Number: 1, Time: start})
+		file.Tracks = append(file.Tracks, t)
+	}
+	if end := chapters[len(chapters)-1].end; end > 0 {
+		file.Duration = end.Seconds()
+	}
+	setPositions(sheet)
+
+	return sheet, roundingErrors, nil
+}
+
+// parseClock parses "[hh:]mm:ss[.fff]" time, comma fraction separator
+// is accepted too.
+func parseClock(s string) (time.Duration, error) {
+	s = strings.Replace(strings.TrimSpace(s), ",", ".", 1)
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("illegal time %s", s)
+	}
+
+	var d time.Duration
+	for _, p := range parts[:len(parts)-1] {
+		v, err := strconv.Atoi(p)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("illegal time %s", s)
+		}
+		d = d*60 + time.Duration(v)
+	}
+
+	// Fraction is parsed separately to keep nanoseconds precision.
+	sec, fraction := parts[len(parts)-1], ""
+	if i := strings.IndexByte(sec, '.'); i >= 0 {
+		sec, fraction = sec[:i], sec[i+1:]
+	}
+	v, err := strconv.Atoi(sec)
+	if err != nil || v < 0 || v >= 60 {
+		return 0, fmt.Errorf("illegal time %s", s)
+	}
+	ns, err := strconv.Atoi((fraction + "000000000")[:9])
+	if err != nil || ns < 0 {
+		return 0, fmt.Errorf("illegal time %s", s)
+	}
+
+	return (d*60+time.Duration(v))*time.Second + time.Duration(ns), nil
+}
+
+// ReadFFMetadata reads chapters from the FFmpeg FFMETADATA1 file.
+// Global title and artist become the disc title and performer.
+func ReadFFMetadata(r io.Reader, opts ImportOptions) (*Sheet, []time.Duration, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !strings.HasPrefix(string(data), ";FFMETADATA1") {
+		return nil, nil, errors.New("FFMETADATA1 header expected")
+	}
+
+	// Split into lines, backslash escapes any character including newline.
+	var lines []string
+	var line []byte
+	for i := 0; i < len(data); i++ {
+		switch c := data[i]; {
+		case c == '\\' && i+1 < len(data):
+			i++
+			line = append(line, '\\', data[i])
+		case c == '\n':
+			lines = append(lines, string(line))
+			line = nil
+		case c != '\r':
+			line = append(line, c)
+		}
+	}
+	lines = append(lines, string(line))
+	unescape := func(s string) string {
+		var b strings.Builder
+		for i := 0; i < len(s); i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+			}
+			b.WriteByte(s[i])
+		}
+		return b.String()
+	}
+
+	sheet := new(Sheet)
+	var (
+		chapters []importedChapter
+		section  string
+		// Current chapter time base and times in its units.
+		num, den   int64
+		start, end int64
+	)
+	toDuration := func(v int64) time.Duration {
+		v *= num
+		return time.Duration(v/den)*time.Second + time.Duration(v%den*int64(time.Second)/den)
+	}
+	finishChapter := func() {
+		if section != "CHAPTER" {
+			return
+		}
+		c := &chapters[len(chapters)-1]
+		c.start = toDuration(start)
+		if end >= 0 {
+			c.end = toDuration(end)
+		}
+	}
+
+	for n, l := range lines {
+		l = trimEscaped(l)
+		if l == "" || l[0] == ';' || l[0] == '#' {
+			continue
+		}
+		if l[0] == '[' && l[len(l)-1] == ']' {
+			finishChapter()
+			section = l[1 : len(l)-1]
+			if section == "CHAPTER" {
+				chapters = append(chapters, importedChapter{end: -1})
+				num, den, start, end = 1, 1000000000, 0, -1
+			}
+			continue
+		}
+
+		i := strings.IndexByte(l, '=')
+		if i < 0 {
+			return nil, nil, fmt.Errorf("line %d: key=value expected", n+1)
+		}
+		key, value := strings.ToLower(l[:i]), unescape(l[i+1:])
+		switch {
+		case section == "" && key == "title":
+			sheet.Title = value
+		case section == "" && key == "artist":
+			sheet.Performer = value
+		case section != "CHAPTER":
+		case key == "timebase":
+			if _, err := fmt.Sscanf(value, "%d/%d", &num, &den); err != nil || num <= 0 || den <= 0 {
+				return nil, nil, fmt.Errorf("line %d: illegal time base %s", n+1, value)
+			}
+		case key == "start", key == "end":
+			v, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "line %d", n+1)
+			}
+			if key == "start" {
+				start = v
+			} else {
+				end = v
+			}
+		case key == "title":
+			chapters[len(chapters)-1].title = value
+		case key == "artist":
+			chapters[len(chapters)-1].performer = value
+		}
+	}
+	finishChapter()
+
+	s, roundingErrors, err := sheetFromChapters(chapters, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.Title, s.Performer = sheet.Title, sheet.Performer
+	return s, roundingErrors, nil
+}
+
+// trimEscaped trims white space around the FFMETADATA1 line, escaped
+// characters (e.g. newline of the multi-line value) are kept.
+func trimEscaped(l string) string {
+	const space = " \t\v\f\r"
+	l = strings.TrimLeft(l, space)
+	end := 0
+	for i := 0; i < len(l); i++ {
+		if l[i] == '\\' && i+1 < len(l) {
+			i++
+			end = i + 1
+		} else if strings.IndexByte(space, l[i]) < 0 {
+			end = i + 1
+		}
+	}
+	return l[:end]
+}
+
+// ReadMatroskaChapters reads chapters of the first edition from the Matroska
+// chapters XML file. The first chapter display string becomes the title.
+func ReadMatroskaChapters(r io.Reader, opts ImportOptions) (*Sheet, []time.Duration, error) {
+	var doc struct {
+		Editions []mkvEditionEntry `xml:"EditionEntry"`
+	}
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to decode Matroska chapters")
+	}
+	if len(doc.Editions) == 0 {
+		return nil, nil, errors.New("no chapters edition found")
+	}
+
+	var chapters []importedChapter
+	for _, atom := range doc.Editions[0].Atoms {
+		c := importedChapter{end: -1}
+		var err error
+		if c.start, err = parseClock(atom.TimeStart); err != nil {
+			return nil, nil, err
+		}
+		if atom.TimeEnd != "" {
+			if c.end, err = parseClock(atom.TimeEnd); err != nil {
+				return nil, nil, err
+			}
+		}
+		if len(atom.Displays) > 0 {
+			c.title = atom.Displays[0].String
+		}
+		chapters = append(chapters, c)
+	}
+
+	return sheetFromChapters(chapters, opts)
+}
+
+// ReadWebVTTChapters reads chapters from the WebVTT file.
+// Multi-line cue text is joined with spaces.
+func ReadWebVTTChapters(r io.Reader, opts ImportOptions) (*Sheet, []time.Duration, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() || !strings.HasPrefix(strings.TrimPrefix(scanner.Text(), "\ufeff"), "WEBVTT") {
+		return nil, nil, errors.New("WEBVTT header expected")
+	}
+
+	var (
+		chapters []importedChapter
+		current  *importedChapter
+		text     []string
+	)
+	for n := 2; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			if current != nil {
+				current.title = strings.Join(text, " ")
+			}
+			current, text = nil, nil
+		case current != nil:
+			text = append(text, line)
+		case strings.Contains(line, "-->"):
+			times := strings.SplitN(line, "-->", 2)
+			fields := strings.Fields(times[1])
+			if len(fields) == 0 {
+				return nil, nil, fmt.Errorf("line %d: cue end time expected", n)
+			}
+			var c importedChapter
+			var err error
+			if c.start, err = parseClock(times[0]); err != nil {
+				return nil, nil, errors.Wrapf(err, "line %d", n)
+			}
+			if c.end, err = parseClock(fields[0]); err != nil {
+				return nil, nil, errors.Wrapf(err, "line %d", n)
+			}
+			chapters = append(chapters, c)
+			current = &chapters[len(chapters)-1]
+		}
+	}
+	if current != nil {
+		current.title = strings.Join(text, " ")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return sheetFromChapters(chapters, opts)
+}
+
+// timestampLine matches "[hh:]mm:ss[.fff] Title" line, the title may be
+// separated by a dash, colon or vertical bar.
+var timestampLine = regexp.MustCompile(`^[\[(]?((?:\d+:)?\d{1,2}:\d{2}(?:[.,]\d+)?)[\])]?(?:\s*[-–—:|]\s*|\s+)(.*)$`)
+
+// ReadTimestampChapters reads chapters from the "mm:ss Title" lines
+// (e.g. YouTube description or Nero chapters). Other lines are ignored.
+func ReadTimestampChapters(r io.Reader, opts ImportOptions) (*Sheet, []time.Duration, error) {
+	var chapters []importedChapter
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		m := timestampLine.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if m == nil {
+			continue
+		}
+		start, err := parseClock(m[1])
+		if err != nil {
+			continue
+		}
+		chapters = append(chapters, importedChapter{start: start, end: -1, title: strings.TrimSpace(m[2])})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return sheetFromChapters(chapters, opts)
+}
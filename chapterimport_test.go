@@ -0,0 +1,119 @@
+package cue
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportChaptersRoundTrip(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(chaptersSheet), 3800)
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+	opts := ImportOptions{File: "mix.flac", FileType: FileTypeWave}
+
+	for _, f := range []struct {
+		name  string
+		write func(io.Writer, *Sheet) error
+		read  func(io.Reader, ImportOptions) (*Sheet, []time.Duration, error)
+	}{
+		{"ffmetadata", WriteFFMetadata, ReadFFMetadata},
+		{"matroska", WriteMatroskaChapters, ReadMatroskaChapters},
+		{"webvtt", WriteWebVTTChapters, ReadWebVTTChapters},
+		{"nero", WriteNeroChapters, ReadTimestampChapters},
+	} {
+		var buf bytes.Buffer
+		if err := f.write(&buf, sheet); err != nil {
+			t.Fatalf("%s: Failed to write chapters. %s", f.name, err.Error())
+		}
+		imported, roundingErrors, err := f.read(&buf, opts)
+		if err != nil {
+			t.Fatalf("%s: Failed to read chapters. %s", f.name, err.Error())
+		}
+
+		tracks := imported.Files[0].Tracks
+		if len(tracks) != 3 || len(roundingErrors) != 3 {
+			t.Fatalf("%s: expected 3 tracks but %d received", f.name, len(tracks))
+		}
+		for i, track := range tracks {
+			if track.StartTime() != sheet.Files[0].Tracks[i].StartTime() {
+				t.Fatalf("%s: expected track %d at %s but %s received", f.name, i+1,
+					sheet.Files[0].Tracks[i].StartTime(), track.StartTime())
+			}
+			if e := roundingErrors[i]; e < -time.Millisecond || e > time.Millisecond {
+				t.Fatalf("%s: unexpected rounding error %s", f.name, e)
+			}
+		}
+		if f.name != "nero" && imported.Files[0].Duration != 3800 {
+			t.Fatalf("%s: expected 3800 seconds duration but %f received", f.name, imported.Files[0].Duration)
+		}
+	}
+}
+
+func TestReadFFMetadataEscapes(t *testing.T) {
+	sheet, err := Parse(strings.NewReader("FILE a.mp3 MP3\n  TRACK 01 AUDIO\n    INDEX 01 00:00:00\n"), 60)
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+	// Escaped newline at the end of the line is kept.
+	sheet.Files[0].Tracks[0].Title = "Two\nlines\n"
+
+	var buf bytes.Buffer
+	if err := WriteFFMetadata(&buf, sheet); err != nil {
+		t.Fatalf("Failed to write chapters. %s", err.Error())
+	}
+	imported, _, err := ReadFFMetadata(&buf, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Failed to read chapters. %s", err.Error())
+	}
+	if title := imported.Files[0].Tracks[0].Title; title != "Two\nlines\n" {
+		t.Fatalf("unexpected title %q", title)
+	}
+}
+
+func TestReadTimestampChapters(t *testing.T) {
+	const description = `Tracklist:
+0:05 Intro
+[03:12] - Track 2
+1:02:03.7 | Finale
+Thanks for listening!
+`
+	for _, tc := range []struct {
+		rounding Rounding
+		frame    int
+		err      time.Duration
+	}{
+		{RoundNearest, 53, 6666667},
+		{RoundDown, 52, -6666667},
+		{RoundUp, 53, 6666667},
+	} {
+		sheet, roundingErrors, err := ReadTimestampChapters(strings.NewReader(description),
+			ImportOptions{File: "video.mp4", FileType: FileTypeMp3, Rounding: tc.rounding})
+		if err != nil {
+			t.Fatalf("Failed to read chapters. %s", err.Error())
+		}
+
+		tracks := sheet.Files[0].Tracks
+		if len(tracks) != 3 || tracks[1].Title != "Track 2" || tracks[2].Title != "Finale" {
+			t.Fatalf("unexpected tracks %+v", tracks)
+		}
+		if idx := tracks[0].Indexes; len(idx) != 2 || idx[0].Number != 0 || idx[1].Time != (Time{0, 5, 0}) {
+			t.Fatalf("expected INDEX 00 and INDEX 01 00:05:00 but %v received", idx)
+		}
+		if start := tracks[2].StartTime(); start != (Time{62, 3, tc.frame}) {
+			t.Fatalf("expected %s but %s received", Time{62, 3, tc.frame}, start)
+		}
+		if roundingErrors[2] != tc.err {
+			t.Fatalf("expected %s rounding error but %s received", tc.err, roundingErrors[2])
+		}
+	}
+
+	for _, text := range []string{"no chapters", "0:01.001 One\n0:01.002 Two\n"} {
+		if _, _, err := ReadTimestampChapters(strings.NewReader(text), ImportOptions{}); err == nil {
+			t.Fatalf("expected error for %q", text)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package cue
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Chapter is the track position in the media made of the sheet files
+// joined one after another.
+type Chapter struct {
+	// Track number.
+	Number int
+	// Track title and performer.
+	Title     string
+	Performer string
+	// Track INDEX 01 position.
+	Start Time
+	// Next track INDEX 01 position or the end of the media, zero if
+	// the last file duration is unknown.
+	End Time
+}
+
+// Chapters returns sheet tracks as chapters. Virtual gaps (PREGAP and
+// POSTGAP) are not a part of the media and are not counted.
+func (s *Sheet) Chapters() ([]Chapter, error) {
+	for i, f := range s.Files {
+		if f.Duration == 0 && i < len(s.Files)-1 {
+			return nil, fmt.Errorf("duration of the file %s is unknown", f.Name)
+		}
+	}
+
+	spans := timeline(s)
+	chapters := make([]Chapter, len(spans))
+	for i, sp := range spans {
+		chapters[i] = Chapter{
+			Number:    sp.track.Number,
+			Title:     sp.track.Title,
+			Performer: sp.track.Performer,
+			Start:     TimeFromFrames(sp.start),
+		}
+		if i > 0 {
+			chapters[i-1].End = chapters[i].Start
+		}
+		if i == len(spans)-1 && sp.end >= 0 {
+			chapters[i].End = TimeFromFrames(sp.end)
+		}
+	}
+
+	return chapters, nil
+}
+
+// name returns chapter name for the single string formats: title prefixed
+// with performer if it differs from the disc performer.
+func (c Chapter) name(discPerformer string) string {
+	title := c.Title
+	if title == "" {
+		title = fmt.Sprintf("Track %02d", c.Number)
+	}
+	if c.Performer != "" && c.Performer != discPerformer {
+		title = c.Performer + " - " + title
+	}
+	return title
+}
+
+// chaptersWithEnd returns chapters and fails if the last chapter end is unknown.
+func chaptersWithEnd(sheet *Sheet) ([]Chapter, error) {
+	chapters, err := sheet.Chapters()
+	if err != nil {
+		return nil, err
+	}
+	if l := len(chapters); l > 0 && chapters[l-1].End.TotalFrames() == 0 {
+		return nil, errors.New("duration of the last file is unknown")
+	}
+	return chapters, nil
+}
+
+// nanoseconds converts time into the nearest number of nanoseconds.
+func (time Time) nanoseconds() int64 {
+	return (int64(time.TotalFrames())*1e9 + framesPerSecond/2) / framesPerSecond
+}
+
+// clock returns time in the hh:mm:ss.fff form with the given number
+// of fraction digits.
+func (time Time) clock(digits int) string {
+	unit := int64(1)
+	for i := digits; i < 9; i++ {
+		unit *= 10
+	}
+	ns := time.nanoseconds()
+	fraction := (ns%1e9 + unit/2) / unit
+	sec := ns / 1e9
+	if limit := 1e9 / unit; fraction == limit {
+		fraction, sec = 0, sec+1
+	}
+	return fmt.Sprintf("%02d:%02d:%02d.%0*d", sec/3600, sec/60%60, sec%60, digits, fraction)
+}
+
+// WriteFFMetadata writes chapters in the FFmpeg FFMETADATA1 format.
+// Chapters time base is a frame, so positions are exact.
+func WriteFFMetadata(w io.Writer, sheet *Sheet) error {
+	chapters, err := chaptersWithEnd(sheet)
+	if err != nil {
+		return err
+	}
+
+	escape := strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", "\\\n")
+	var buf bytes.Buffer
+	writeTag := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(&buf, "%s=%s\n", key, escape.Replace(value))
+		}
+	}
+
+	buf.WriteString(";FFMETADATA1\n")
+	writeTag("title", sheet.Title)
+	writeTag("artist", sheet.Performer)
+	for _, c := range chapters {
+		fmt.Fprintf(&buf, "\n[CHAPTER]\nTIMEBASE=1/%d\nSTART=%d\nEND=%d\n",
+			framesPerSecond, c.Start.TotalFrames(), c.End.TotalFrames())
+		writeTag("title", c.Title)
+		writeTag("artist", c.Performer)
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// Matroska chapters XML elements.
+type (
+	mkvChapters struct {
+		XMLName xml.Name        `xml:"Chapters"`
+		Edition mkvEditionEntry `xml:"EditionEntry"`
+	}
+	mkvEditionEntry struct {
+		Atoms []mkvChapterAtom `xml:"ChapterAtom"`
+	}
+	mkvChapterAtom struct {
+		UID       int                 `xml:"ChapterUID"`
+		TimeStart string              `xml:"ChapterTimeStart"`
+		TimeEnd   string              `xml:"ChapterTimeEnd,omitempty"`
+		Displays  []mkvChapterDisplay `xml:"ChapterDisplay"`
+	}
+	mkvChapterDisplay struct {
+		String   string `xml:"ChapterString"`
+		Language string `xml:"ChapterLanguage"`
+	}
+)
+
+// WriteMatroskaChapters writes chapters in the Matroska chapters XML format
+// (mkvmerge --chapters). ChapterTimeEnd of the last chapter is omitted
+// if the last file duration is unknown.
+func WriteMatroskaChapters(w io.Writer, sheet *Sheet) error {
+	chapters, err := sheet.Chapters()
+	if err != nil {
+		return err
+	}
+
+	var doc mkvChapters
+	for _, c := range chapters {
+		atom := mkvChapterAtom{
+			UID:       c.Number,
+			TimeStart: c.Start.clock(9),
+			Displays:  []mkvChapterDisplay{{c.name(sheet.Performer), "und"}},
+		}
+		if c.End.TotalFrames() != 0 {
+			atom.TimeEnd = c.End.clock(9)
+		}
+		doc.Edition.Atoms = append(doc.Edition.Atoms, atom)
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString("<!DOCTYPE Chapters SYSTEM \"matroskachapters.dtd\">\n")
+	buf.Write(data)
+	buf.WriteByte('\n')
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// WriteNeroChapters writes chapters in the Nero (QuickTime) chapters text
+// format: "hh:mm:ss.mmm Title" lines, as used by mp4chaps.
+func WriteNeroChapters(w io.Writer, sheet *Sheet) error {
+	chapters, err := sheet.Chapters()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, c := range chapters {
+		fmt.Fprintf(&buf, "%s %s\n", c.Start.clock(3), oneLine(c.name(sheet.Performer)))
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// WriteWebVTTChapters writes chapters in the WebVTT format.
+func WriteWebVTTChapters(w io.Writer, sheet *Sheet) error {
+	chapters, err := chaptersWithEnd(sheet)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("WEBVTT\n")
+	for _, c := range chapters {
+		// Cue text can't contain "-->" and empty lines.
+		name := strings.Replace(oneLine(c.name(sheet.Performer)), "-->", "->", -1)
+		fmt.Fprintf(&buf, "\n%d\n%s --> %s\n%s\n", c.Number, c.Start.clock(3), c.End.clock(3), name)
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// WriteYouTubeChapters writes chapters as YouTube description timestamps:
+// "m:ss Title" lines, hours are added for the long media.
+func WriteYouTubeChapters(w io.Writer, sheet *Sheet) error {
+	chapters, err := sheet.Chapters()
+	if err != nil {
+		return err
+	}
+
+	long := false
+	for _, c := range chapters {
+		long = long || c.Start.Min >= 60
+	}
+
+	var buf bytes.Buffer
+	for _, c := range chapters {
+		sec := c.Start.Min*60 + c.Start.Sec
+		if long {
+			fmt.Fprintf(&buf, "%d:%02d:%02d", sec/3600, sec/60%60, sec%60)
+		} else {
+			fmt.Fprintf(&buf, "%d:%02d", sec/60, sec%60)
+		}
+		fmt.Fprintf(&buf, " %s\n", oneLine(c.name(sheet.Performer)))
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// oneLine replaces line breaks and other whitespace sequences with single spaces.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
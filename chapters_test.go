@@ -0,0 +1,110 @@
+package cue
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const chaptersSheet = `PERFORMER "DJ"
+TITLE "Mix"
+FILE "mix.flac" WAVE
+  TRACK 01 AUDIO
+    TITLE "Intro"
+    PERFORMER "DJ"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Song = One"
+    PERFORMER "Guest"
+    INDEX 00 03:20:00
+    INDEX 01 03:22:40
+  TRACK 03 AUDIO
+    INDEX 01 62:00:74
+`
+
+func TestChapters(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(chaptersSheet))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	chapters, err := sheet.Chapters()
+	if err != nil {
+		t.Fatalf("Failed to get chapters. %s", err.Error())
+	}
+	if len(chapters) != 3 || chapters[1].Start != (Time{3, 22, 40}) || chapters[0].End != chapters[1].Start {
+		t.Fatalf("unexpected chapters %+v", chapters)
+	}
+	if chapters[2].End != (Time{}) {
+		t.Fatalf("expected unknown last chapter end but %s received", chapters[2].End)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteFFMetadata(&buf, sheet); err == nil {
+		t.Fatalf("expected error for unknown duration")
+	}
+	if err := WriteWebVTTChapters(&buf, sheet); err == nil {
+		t.Fatalf("expected error for unknown duration")
+	}
+
+	buf.Reset()
+	if err := WriteYouTubeChapters(&buf, sheet); err != nil {
+		t.Fatalf("Failed to write chapters. %s", err.Error())
+	}
+	expected := "0:00:00 Intro\n0:03:22 Guest - Song = One\n1:02:00 Track 03\n"
+	if buf.String() != expected {
+		t.Fatalf("expected\n%s\nbut\n%s\nreceived", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteNeroChapters(&buf, sheet); err != nil {
+		t.Fatalf("Failed to write chapters. %s", err.Error())
+	}
+	expected = "00:00:00.000 Intro\n00:03:22.533 Guest - Song = One\n01:02:00.987 Track 03\n"
+	if buf.String() != expected {
+		t.Fatalf("expected\n%s\nbut\n%s\nreceived", expected, buf.String())
+	}
+}
+
+func TestChaptersWithDuration(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(chaptersSheet), 3800)
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	var buf bytes.Buffer
+	if err := WriteFFMetadata(&buf, sheet); err != nil {
+		t.Fatalf("Failed to write chapters. %s", err.Error())
+	}
+	for _, s := range []string{
+		";FFMETADATA1\ntitle=Mix\nartist=DJ\n",
+		"[CHAPTER]\nTIMEBASE=1/75\nSTART=15190\nEND=279074\ntitle=Song \\= One\nartist=Guest\n",
+		"START=279074\nEND=285000\n",
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Fatalf("expected %q in\n%s", s, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := WriteWebVTTChapters(&buf, sheet); err != nil {
+		t.Fatalf("Failed to write chapters. %s", err.Error())
+	}
+	if s := "\n2\n00:03:22.533 --> 01:02:00.987\nGuest - Song = One\n"; !strings.Contains(buf.String(), s) {
+		t.Fatalf("expected %q in\n%s", s, buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteMatroskaChapters(&buf, sheet); err != nil {
+		t.Fatalf("Failed to write chapters. %s", err.Error())
+	}
+	for _, s := range []string{
+		"<ChapterTimeStart>00:03:22.533333333</ChapterTimeStart>",
+		"<ChapterTimeEnd>01:03:20.000000000</ChapterTimeEnd>",
+		"<ChapterString>Guest - Song = One</ChapterString>",
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Fatalf("expected %q in\n%s", s, buf.String())
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Command cuefmt formats cue sheets in the canonical layout.
+//
+// Usage:
+//
+//	cuefmt [flags] [path ...]
+//
+// Without paths it formats the standard input. Directories are processed
+// recursively, only *.cue files are formatted. By default the formatted
+// sheets are written to the standard output. The flags are:
+//
+//	-d  print diffs of the sheets which formatting differs from cuefmt's
+//	-l  list files which formatting differs from cuefmt's
+//	-w  write result to the source file instead of stdout
+//
+// Like gofmt, cuefmt exits with code 2 if any sheet can't be read or
+// parsed, so CI checks test the output of -l instead.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	cue "github.com/tomoconnor/cue-go"
+)
+
+// options are cuefmt flags.
+type options struct {
+	list, diff, write bool
+}
+
+// env is the command environment.
+type env struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+	failed         bool
+}
+
+func (e *env) report(err error) {
+	fmt.Fprintln(e.stderr, err)
+	e.failed = true
+}
+
+// process formats the sheet read from r, name is used in the output.
+func (e *env) process(opts options, name string, r io.Reader, info fs.FileInfo) error {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	res, err := cue.Format(src)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+
+	if !bytes.Equal(src, res) {
+		if opts.list {
+			fmt.Fprintln(e.stdout, name)
+		}
+		if opts.write {
+			if info == nil {
+				return fmt.Errorf("%s: can't write result to the standard input", name)
+			}
+			if err := os.WriteFile(name, res, info.Mode().Perm()); err != nil {
+				return err
+			}
+		}
+		if opts.diff {
+			fmt.Fprintf(e.stdout, "diff %s cuefmt/%s\n", name, name)
+			writeDiff(e.stdout, name, splitLines(string(src)), splitLines(string(res)))
+		}
+	}
+	if !opts.list && !opts.diff && !opts.write {
+		_, err = e.stdout.Write(res)
+	}
+	return err
+}
+
+// processFile formats the sheet file.
+func (e *env) processFile(opts options, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return e.process(opts, name, f, info)
+}
+
+// processPath formats the file or the *.cue files of the directory tree.
+func (e *env) processPath(opts options, path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		e.report(err)
+		return
+	}
+	if !info.IsDir() {
+		if err := e.processFile(opts, path); err != nil {
+			e.report(err)
+		}
+		return
+	}
+
+	err = filepath.WalkDir(path, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			e.report(err)
+			return nil
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(name), ".cue") {
+			return nil
+		}
+		if err := e.processFile(opts, name); err != nil {
+			e.report(err)
+		}
+		return nil
+	})
+	if err != nil {
+		e.report(err)
+	}
+}
+
+// run runs the command line and returns the exit code.
+func run(e *env, args []string) int {
+	flags := flag.NewFlagSet("cuefmt", flag.ContinueOnError)
+	flags.SetOutput(e.stderr)
+	var opts options
+	flags.BoolVar(&opts.diff, "d", false, "print diffs instead of rewriting files")
+	flags.BoolVar(&opts.list, "l", false, "list files whose formatting differs from cuefmt's")
+	flags.BoolVar(&opts.write, "w", false, "write result to (source) file instead of stdout")
+	flags.Usage = func() {
+		fmt.Fprintln(e.stderr, "usage: cuefmt [flags] [path ...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		if opts.write {
+			e.report(fmt.Errorf("error: cannot use -w with standard input"))
+			return 2
+		}
+		if err := e.process(opts, "<standard input>", e.stdin, nil); err != nil {
+			e.report(err)
+		}
+	}
+	for _, path := range flags.Args() {
+		e.processPath(opts, path)
+	}
+
+	if e.failed {
+		return 2
+	}
+	return 0
+}
+
+func main() {
+	os.Exit(run(&env{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}, os.Args[1:]))
+}
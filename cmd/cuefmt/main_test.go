@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	messySheet = "title 'Album'\r\nfile \"album.wav\" wave\r\ntrack 1 audio\r\nindex 1 00:00:00\r\n"
+	tidySheet  = "TITLE Album\nFILE album.wav WAVE\n  TRACK 01 AUDIO\n    INDEX 01 00:00:00\n"
+)
+
+// runTest runs the command line and returns exit code, stdout and stderr.
+func runTest(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(&env{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr}, args)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestStdin(t *testing.T) {
+	if code, stdout, _ := runTest(messySheet); code != 0 || stdout != tidySheet {
+		t.Fatalf("unexpected result %d\n%s", code, stdout)
+	}
+	if code, stdout, _ := runTest(tidySheet, "-l"); code != 0 || stdout != "" {
+		t.Fatalf("unexpected result %d %q", code, stdout)
+	}
+	if code, _, stderr := runTest("TRACK", "-l"); code != 2 || stderr == "" {
+		t.Fatalf("unexpected result %d %q", code, stderr)
+	}
+	if code, _, _ := runTest(messySheet, "-w"); code != 2 {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	messy := filepath.Join(dir, "a", "messy.cue")
+	tidy := filepath.Join(dir, "tidy.cue")
+	os.Mkdir(filepath.Dir(messy), 0755)
+	for name, data := range map[string]string{messy: messySheet, tidy: tidySheet, filepath.Join(dir, "notes.txt"): "x"} {
+		if err := os.WriteFile(name, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if code, stdout, _ := runTest("", "-l", dir); code != 0 || stdout != messy+"\n" {
+		t.Fatalf("unexpected list %d %q", code, stdout)
+	}
+
+	code, stdout, _ := runTest("", "-d", messy)
+	expected := "diff " + messy + " cuefmt/" + messy + "\n" +
+		"--- " + messy + ".orig\n+++ " + messy + "\n" +
+		"@@ -1,4 +1,4 @@\n" +
+		"-title 'Album'\r\n-file \"album.wav\" wave\r\n-track 1 audio\r\n-index 1 00:00:00\r\n" +
+		"+TITLE Album\n+FILE album.wav WAVE\n+  TRACK 01 AUDIO\n+    INDEX 01 00:00:00\n"
+	if code != 0 || stdout != expected {
+		t.Fatalf("unexpected diff %d\n%s", code, stdout)
+	}
+
+	if code, _, stderr := runTest("", "-w", dir); code != 0 {
+		t.Fatalf("unexpected result %d %q", code, stderr)
+	}
+	if data, _ := os.ReadFile(messy); string(data) != tidySheet {
+		t.Fatalf("unexpected written sheet\n%s", data)
+	}
+	if code, _, _ := runTest("", "-l", filepath.Join(dir, "missing.cue")); code != 2 {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	var a, b []string
+	for i := 1; i <= 20; i++ {
+		a = append(a, strings.Repeat("x", i)+"\n")
+	}
+	b = append(b, a...)
+	b[1] = "changed\n"
+	b = append(b[:15], b[16:]...)
+
+	var buf bytes.Buffer
+	writeDiff(&buf, "f", a, b)
+	expected := "--- f.orig\n+++ f\n" +
+		"@@ -1,5 +1,5 @@\n x\n-xx\n+changed\n xxx\n xxxx\n xxxxx\n" +
+		"@@ -13,7 +13,6 @@\n" +
+		" " + strings.Repeat("x", 13) + "\n " + strings.Repeat("x", 14) + "\n " + strings.Repeat("x", 15) + "\n" +
+		"-" + strings.Repeat("x", 16) + "\n" +
+		" " + strings.Repeat("x", 17) + "\n " + strings.Repeat("x", 18) + "\n " + strings.Repeat("x", 19) + "\n"
+	if buf.String() != expected {
+		t.Fatalf("unexpected diff\n%s", buf.String())
+	}
+}
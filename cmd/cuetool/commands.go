@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	cue "github.com/tomoconnor/cue-go"
+)
+
+var profiles = map[string]cue.Profile{
+	cue.ProfileRedBookAudio.Name:    cue.ProfileRedBookAudio,
+	cue.ProfileMixedMode.Name:       cue.ProfileMixedMode,
+	cue.ProfileEnhancedCD.Name:      cue.ProfileEnhancedCD,
+	cue.ProfileLenientPlayback.Name: cue.ProfileLenientPlayback,
+}
+
+// parseFlags parses the command flags, returns false and the exit code on failure.
+func parseFlags(flags *flag.FlagSet, args []string) (int, bool) {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK, false
+		}
+		return exitUsage, false
+	}
+	return exitOK, true
+}
+
+// importFlags adds flags of the chapters and labels importers.
+func importFlags(flags *flag.FlagSet, opts *readOptions) {
+	flags.StringVar(&opts.File, "media", "", "media `file` name of the imported chapters or labels")
+	flags.Func("type", "media file `type` of the imported chapters or labels (default BINARY)", func(s string) (err error) {
+		opts.FileType, err = cue.ParseFileType(strings.ToUpper(s))
+		return err
+	})
+	flags.Func("rounding", "rounding of the imported times to frames: nearest, down or up", func(s string) error {
+		switch s {
+		case "nearest":
+			opts.Rounding = cue.RoundNearest
+		case "down":
+			opts.Rounding = cue.RoundDown
+		case "up":
+			opts.Rounding = cue.RoundUp
+		default:
+			return fmt.Errorf("unknown rounding %q", s)
+		}
+		return nil
+	})
+}
+
+func runValidate(e *env, args []string) int {
+	flags := e.flagSet("validate", "[file]")
+	profile := flags.String("profile", cue.ProfileRedBookAudio.Name,
+		"validation profile: redbook-audio, mixed-mode, enhanced-cd or lenient-playback")
+	media := flags.Bool("media", false, "read durations of the WAVE and BINARY files next to the sheet")
+	strict := flags.Bool("strict", false, "fail on warnings too")
+	format := flags.String("from", "", "input `format` (default by extension or cue)")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	name, ok := e.inputArg(flags)
+	if !ok {
+		return exitUsage
+	}
+	p, ok := profiles[*profile]
+	if !ok {
+		e.errorf("unknown profile %q", *profile)
+		return exitUsage
+	}
+
+	sheet, err := readSheet(e, *format, readOptions{name: name})
+	if err != nil {
+		e.errorf("%v", err)
+		return exitFailure
+	}
+	if *media {
+		if err := sheet.ReadDurations(os.DirFS(dir(name))); err != nil {
+			e.errorf("%v", err)
+			return exitFailure
+		}
+	}
+
+	code := exitOK
+	for _, f := range cue.Validate(sheet, p) {
+		fmt.Fprintf(e.stdout, "%s: %s\n", name, f)
+		if f.Severity == cue.SeverityError || *strict {
+			code = exitFailure
+		}
+	}
+	return code
+}
+
+func runDump(e *env, args []string) int {
+	flags := e.flagSet("dump", "[file]")
+	output := flags.String("format", "table", "output `format`: json, yaml or table")
+	format := flags.String("from", "", "input `format` (default by extension or cue)")
+	var opts readOptions
+	importFlags(flags, &opts)
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	var ok bool
+	if opts.name, ok = e.inputArg(flags); !ok {
+		return exitUsage
+	}
+
+	var dump func(io.Writer, *cue.Sheet) error
+	switch *output {
+	case "json":
+		dump = func(w io.Writer, sheet *cue.Sheet) error {
+			return writers["json"](w, sheet, writeOptions{})
+		}
+	case "yaml":
+		dump = writeYAML
+	case "table":
+		dump = writeTable
+	default:
+		e.errorf("unknown dump format %q", *output)
+		return exitUsage
+	}
+
+	sheet, err := readSheet(e, *format, opts)
+	if err != nil {
+		e.errorf("%v", err)
+		return exitFailure
+	}
+	if err := dump(e.stdout, sheet); err != nil {
+		e.errorf("%v", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+func runFmt(e *env, args []string) int {
+	flags := e.flagSet("fmt", "[file]")
+	write := flags.Bool("w", false, "write result to the source file instead of stdout")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	name, ok := e.inputArg(flags)
+	if !ok {
+		return exitUsage
+	}
+	if *write && name == "-" {
+		e.errorf("can't write result to the standard input")
+		return exitUsage
+	}
+
+	r, err := e.open(name)
+	if err != nil {
+		e.errorf("%v", err)
+		return exitFailure
+	}
+	src, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		e.errorf("%v", err)
+		return exitFailure
+	}
+	res, err := cue.Format(src)
+	if err != nil {
+		e.errorf("%s: %v", name, err)
+		return exitFailure
+	}
+
+	if *write {
+		var info os.FileInfo
+		if info, err = os.Stat(name); err == nil {
+			err = os.WriteFile(name, res, info.Mode().Perm())
+		}
+	} else {
+		_, err = e.stdout.Write(res)
+	}
+	if err != nil {
+		e.errorf("%v", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+func runConvert(e *env, args []string) int {
+	flags := e.flagSet("convert", "[file]")
+	from := flags.String("from", "", "input `format`: "+formatNames(readers)+" (default by extension or cue)")
+	to := flags.String("to", "", "output `format`: "+formatNames(writers)+" (default by -o extension or cue)")
+	output := flags.String("o", "-", "output `file`")
+	gaps := flags.Bool("gaps", false, "write tracks gaps as separate labels (audacity, markers)")
+	var opts readOptions
+	importFlags(flags, &opts)
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	var ok bool
+	if opts.name, ok = e.inputArg(flags); !ok {
+		return exitUsage
+	}
+
+	toFormat, err := detectFormat(*to, *output, "cue")
+	if err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+	write, ok := writers[toFormat]
+	if !ok {
+		e.errorf("unknown output format %q (%s)", toFormat, formatNames(writers))
+		return exitUsage
+	}
+
+	sheet, err := readSheet(e, *from, opts)
+	if err != nil {
+		e.errorf("%v", err)
+		return exitFailure
+	}
+	var buf bytes.Buffer
+	wopts := writeOptions{sheetDir: dir(opts.name), outDir: dir(*output), gaps: *gaps}
+	if err := write(&buf, sheet, wopts); err != nil {
+		e.errorf("%v", err)
+		return exitFailure
+	}
+
+	if *output == "-" {
+		_, err = e.stdout.Write(buf.Bytes())
+	} else {
+		err = os.WriteFile(*output, buf.Bytes(), 0644)
+	}
+	if err != nil {
+		e.errorf("%v", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+func runSplit(e *env, args []string) int {
+	flags := e.flagSet("split", "file")
+	out := flags.String("o", ".", "output `directory`")
+	dryRun := flags.Bool("n", false, "print tracks files names without writing them")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	if flags.NArg() != 1 || flags.Arg(0) == "-" {
+		flags.Usage()
+		return exitUsage
+	}
+	name := flags.Arg(0)
+
+	sheet, err := readSheet(e, "", readOptions{name: name})
+	if err != nil {
+		e.errorf("%v", err)
+		return exitFailure
+	}
+	tfs, err := cue.NewTrackFS(sheet, os.DirFS(dir(name)))
+	if err != nil {
+		e.errorf("%v", err)
+		return exitFailure
+	}
+	entries, err := fs.ReadDir(tfs, ".")
+	if err != nil {
+		e.errorf("%v", err)
+		return exitFailure
+	}
+	if len(entries) == 0 {
+		e.errorf("%s: no audio tracks to split", name)
+		return exitFailure
+	}
+
+	if !*dryRun {
+		if err := os.MkdirAll(*out, 0755); err != nil {
+			e.errorf("%v", err)
+			return exitFailure
+		}
+	}
+	for _, entry := range entries {
+		path := filepath.Join(*out, entry.Name())
+		fmt.Fprintln(e.stdout, path)
+		if *dryRun {
+			continue
+		}
+		if err := copyTrack(tfs, entry.Name(), path); err != nil {
+			e.errorf("%v", err)
+			return exitFailure
+		}
+	}
+	return exitOK
+}
+
+// copyTrack writes the track file of the track file system to the path.
+func copyTrack(fsys fs.FS, name, path string) error {
+	src, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+func runDiscID(e *env, args []string) int {
+	flags := e.flagSet("discid", "[file]")
+	kind := flags.String("id", "", "print only the `kind` of disc ID: cddb or musicbrainz")
+	format := flags.String("from", "", "input `format` (default by extension or cue)")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	name, ok := e.inputArg(flags)
+	if !ok {
+		return exitUsage
+	}
+	if *kind != "" && *kind != "cddb" && *kind != "musicbrainz" {
+		e.errorf("unknown disc ID kind %q", *kind)
+		return exitUsage
+	}
+
+	sheet, err := readSheet(e, *format, readOptions{name: name})
+	if err != nil {
+		e.errorf("%v", err)
+		return exitFailure
+	}
+	// Durations of the sheets converted from the other formats may be known,
+	// so files which can't be read are reported and their durations are kept.
+	if err := sheet.ReadDurations(os.DirFS(dir(name))); err != nil {
+		e.errorf("%v, duration is unknown", err)
+	}
+
+	ids := []struct {
+		kind, label string
+		id          func() (string, error)
+	}{
+		{"cddb", "CDDB", sheet.CDDBDiscID},
+		{"musicbrainz", "MusicBrainz", sheet.MusicBrainzDiscID},
+	}
+	for _, i := range ids {
+		if *kind != "" && *kind != i.kind {
+			continue
+		}
+		id, err := i.id()
+		if err != nil {
+			e.errorf("%s: %v", name, err)
+			return exitFailure
+		}
+		if *kind != "" {
+			fmt.Fprintln(e.stdout, id)
+		} else {
+			fmt.Fprintf(e.stdout, "%s: %s\n", i.label, id)
+		}
+	}
+	return exitOK
+}
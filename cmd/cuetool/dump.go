@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"text/tabwriter"
+
+	cue "github.com/tomoconnor/cue-go"
+)
+
+// yamlNode is the JSON value with the object keys order preserved.
+type yamlNode struct {
+	// Scalar value in the JSON form, empty for objects and arrays.
+	scalar string
+	isList bool
+	keys   []string
+	values []*yamlNode
+}
+
+// decodeYAMLNode decodes the next JSON value.
+func decodeYAMLNode(dec *json.Decoder) (*yamlNode, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		node := &yamlNode{isList: t == '['}
+		for dec.More() {
+			if !node.isList {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.keys = append(node.keys, key.(string))
+			}
+			value, err := decodeYAMLNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			node.values = append(node.values, value)
+		}
+		// Closing delimiter.
+		_, err := dec.Token()
+		return node, err
+	case nil:
+		return &yamlNode{scalar: "null"}, nil
+	default:
+		// Strings are written as the JSON strings, which are valid YAML
+		// double-quoted scalars.
+		data, err := json.Marshal(t)
+		return &yamlNode{scalar: string(data)}, err
+	}
+}
+
+// write writes the node at the indent, inline values follow their keys.
+func (n *yamlNode) write(buf *bytes.Buffer, indent string, inline bool) {
+	switch {
+	case n.scalar != "":
+		fmt.Fprintf(buf, " %s\n", n.scalar)
+		return
+	case len(n.values) == 0 && n.isList:
+		buf.WriteString(" []\n")
+		return
+	case len(n.values) == 0:
+		buf.WriteString(" {}\n")
+		return
+	}
+
+	for i, value := range n.values {
+		prefix := indent
+		if i == 0 && inline {
+			// The first item of the list item object follows the dash.
+			prefix = " "
+		} else if i == 0 {
+			buf.WriteByte('\n')
+		}
+		if n.isList {
+			buf.WriteString(prefix + "-")
+			value.write(buf, indent+"  ", value.scalar == "" && !value.isList && len(value.values) > 0)
+			continue
+		}
+		buf.WriteString(prefix + n.keys[i] + ":")
+		value.write(buf, indent+"  ", false)
+	}
+}
+
+// writeYAML writes the sheet as YAML document with the JSON keys.
+func writeYAML(w io.Writer, sheet *cue.Sheet) error {
+	data, err := json.Marshal(sheet)
+	if err != nil {
+		return err
+	}
+	root, err := decodeYAMLNode(json.NewDecoder(bytes.NewReader(data)))
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("---")
+	root.write(&buf, "", false)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// writeTable writes the disc information and the tracks table.
+func writeTable(w io.Writer, sheet *cue.Sheet) error {
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 8, 2, ' ', 0)
+	for _, field := range []struct{ name, value string }{
+		{"Title", sheet.Title},
+		{"Performer", sheet.Performer},
+		{"Songwriter", sheet.Songwriter},
+		{"Catalog", sheet.Catalog},
+	} {
+		if field.value != "" {
+			fmt.Fprintf(tw, "%s:\t%s\n", field.name, field.value)
+		}
+	}
+	tw.Flush()
+	if buf.Len() > 0 {
+		buf.WriteByte('\n')
+	}
+
+	fmt.Fprintln(tw, "#\tTYPE\tSTART\tLENGTH\tPERFORMER\tTITLE\tFILE")
+	for _, f := range sheet.Files {
+		for _, t := range f.Tracks {
+			length := "-"
+			if t.EndPosition > t.StartPosition {
+				frames := int(math.Floor(t.Duration()*75 + 0.5))
+				length = cue.TimeFromFrames(frames).String()
+			}
+			fmt.Fprintf(tw, "%02d\t%s\t%s\t%s\t%s\t%s\t%s\n", t.Number, t.DataType, t.StartTime(), length,
+				cell(t.Performer), cell(t.Title), f.Name)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// cell returns single line table cell text.
+func cell(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	cue "github.com/tomoconnor/cue-go"
+)
+
+// readOptions are the options of the sheet readers.
+type readOptions struct {
+	// Name of the input file, "-" for stdin.
+	name string
+	// Options of the chapters and labels importers.
+	cue.ImportOptions
+}
+
+// writeOptions are the options of the sheet writers.
+type writeOptions struct {
+	// Directories of the input sheet and the output file.
+	sheetDir, outDir string
+	// Write tracks gaps as separate labels.
+	gaps bool
+}
+
+type (
+	reader func(r io.Reader, opts readOptions) (*cue.Sheet, error)
+	writer func(w io.Writer, sheet *cue.Sheet, opts writeOptions) error
+)
+
+var readers = map[string]reader{
+	"cue": func(r io.Reader, opts readOptions) (*cue.Sheet, error) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		text, err := cue.DecodeText(data)
+		if err != nil {
+			return nil, err
+		}
+		return cue.Parse(strings.NewReader(text))
+	},
+	"toc": func(r io.Reader, opts readOptions) (*cue.Sheet, error) {
+		return cue.ParseTOC(r)
+	},
+	"ccd": func(r io.Reader, opts readOptions) (*cue.Sheet, error) {
+		image := strings.TrimSuffix(filepath.Base(opts.name), filepath.Ext(opts.name)) + ".img"
+		return cue.ParseCCD(r, image)
+	},
+	"json": func(r io.Reader, opts readOptions) (*cue.Sheet, error) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		sheet := new(cue.Sheet)
+		if err := json.Unmarshal(data, sheet); err != nil {
+			return nil, err
+		}
+		sheet.UpdatePositions()
+		return sheet, nil
+	},
+	"flac": func(r io.Reader, opts readOptions) (*cue.Sheet, error) {
+		return cue.ReadFLACCueSheet(r)
+	},
+	"ape": func(r io.Reader, opts readOptions) (*cue.Sheet, error) {
+		ra, size, err := readerAt(r)
+		if err != nil {
+			return nil, err
+		}
+		return cue.ReadAPECueSheet(ra, size)
+	},
+	"wav": func(r io.Reader, opts readOptions) (*cue.Sheet, error) {
+		ra, size, err := readerAt(r)
+		if err != nil {
+			return nil, err
+		}
+		if opts.File == "" {
+			opts.File = filepath.Base(opts.name)
+		}
+		sheet, _, err := cue.ReadWaveCues(ra, size, opts.ImportOptions)
+		return sheet, err
+	},
+	"ffmetadata": imported(cue.ReadFFMetadata),
+	"matroska":   imported(cue.ReadMatroskaChapters),
+	"webvtt":     imported(cue.ReadWebVTTChapters),
+	"timestamps": imported(cue.ReadTimestampChapters),
+	"audacity":   imported(cue.ReadAudacityLabels),
+	"markers":    imported(cue.ReadMarkersCSV),
+}
+
+// imported adapts chapters and labels importer, rounding errors are dropped.
+func imported(read func(io.Reader, cue.ImportOptions) (*cue.Sheet, []time.Duration, error)) reader {
+	return func(r io.Reader, opts readOptions) (*cue.Sheet, error) {
+		sheet, _, err := read(r, opts.ImportOptions)
+		return sheet, err
+	}
+}
+
+// readerAt returns random access reader of the input and its size. Regular
+// files are read in place, the other inputs (e.g. stdin) are read into memory.
+func readerAt(r io.Reader) (io.ReaderAt, int64, error) {
+	if f, ok := r.(*os.File); ok {
+		info, err := f.Stat()
+		if err != nil {
+			return nil, 0, err
+		}
+		if info.Mode().IsRegular() {
+			return f, info.Size(), nil
+		}
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(data), int64(len(data)), nil
+}
+
+var writers = map[string]writer{
+	"cue": func(w io.Writer, sheet *cue.Sheet, opts writeOptions) error {
+		return cue.Write(w, sheet)
+	},
+	"toc": func(w io.Writer, sheet *cue.Sheet, opts writeOptions) error {
+		return cue.WriteTOC(w, sheet)
+	},
+	"ccd": func(w io.Writer, sheet *cue.Sheet, opts writeOptions) error {
+		if len(sheet.Files) != 1 {
+			return fmt.Errorf("single file sheet expected, but %d files found", len(sheet.Files))
+		}
+		info, err := os.Stat(filepath.Join(opts.sheetDir, sheet.Files[0].Name))
+		if err != nil {
+			return err
+		}
+		return cue.WriteCCD(w, sheet, info.Size())
+	},
+	"json": func(w io.Writer, sheet *cue.Sheet, opts writeOptions) error {
+		data, err := json.MarshalIndent(sheet, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	},
+	"ffmetadata": chapters(cue.WriteFFMetadata),
+	"matroska":   chapters(cue.WriteMatroskaChapters),
+	"nero":       chapters(cue.WriteNeroChapters),
+	"webvtt":     chapters(cue.WriteWebVTTChapters),
+	"youtube":    chapters(cue.WriteYouTubeChapters),
+	"audacity": func(w io.Writer, sheet *cue.Sheet, opts writeOptions) error {
+		return cue.WriteAudacityLabels(w, sheet, opts.gaps)
+	},
+	"markers": func(w io.Writer, sheet *cue.Sheet, opts writeOptions) error {
+		return cue.WriteMarkersCSV(w, sheet, opts.gaps)
+	},
+	"m3u8": playlist(cue.WriteM3U8),
+	"xspf": playlist(cue.WriteXSPF),
+	"pls":  playlist(cue.WritePLS),
+}
+
+// chapters adapts chapters writer.
+func chapters(write func(io.Writer, *cue.Sheet) error) writer {
+	return func(w io.Writer, sheet *cue.Sheet, opts writeOptions) error {
+		return write(w, sheet)
+	}
+}
+
+// playlist adapts playlist writer.
+func playlist(write func(io.Writer, *cue.Sheet, string, string) error) writer {
+	return func(w io.Writer, sheet *cue.Sheet, opts writeOptions) error {
+		return write(w, sheet, opts.sheetDir, opts.outDir)
+	}
+}
+
+// extensions maps files extensions to the formats.
+var extensions = map[string]string{
+	".cue":        "cue",
+	".toc":        "toc",
+	".ccd":        "ccd",
+	".json":       "json",
+	".flac":       "flac",
+	".ape":        "ape",
+	".wav":        "wav",
+	".ffmetadata": "ffmetadata",
+	".xml":        "matroska",
+	".vtt":        "webvtt",
+	".csv":        "markers",
+	".m3u8":       "m3u8",
+	".xspf":       "xspf",
+	".pls":        "pls",
+}
+
+// detectFormat returns the format given explicitly or by the file extension.
+func detectFormat(format, name, fallback string) (string, error) {
+	if format != "" {
+		return format, nil
+	}
+	if name == "-" || name == "" {
+		return fallback, nil
+	}
+	if f, ok := extensions[strings.ToLower(filepath.Ext(name))]; ok {
+		return f, nil
+	}
+	return "", fmt.Errorf("can't detect format of %s, specify it explicitly", name)
+}
+
+// formatNames returns sorted names of the formats.
+func formatNames(formats interface{}) string {
+	var names []string
+	switch m := formats.(type) {
+	case map[string]reader:
+		for name := range m {
+			names = append(names, name)
+		}
+	case map[string]writer:
+		for name := range m {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// readSheet reads the sheet of the given format from the file or stdin.
+func readSheet(e *env, format string, opts readOptions) (*cue.Sheet, error) {
+	format, err := detectFormat(format, opts.name, "cue")
+	if err != nil {
+		return nil, err
+	}
+	read, ok := readers[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown input format %q (%s)", format, formatNames(readers))
+	}
+
+	r, err := e.open(opts.name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	sheet, err := read(r, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", opts.name, err)
+	}
+	return sheet, nil
+}
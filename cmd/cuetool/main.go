@@ -0,0 +1,134 @@
+// Command cuetool validates, dumps, formats, converts and splits cue sheets.
+//
+// Usage:
+//
+//	cuetool <command> [flags] [file]
+//
+// The commands are:
+//
+//	validate  check the sheet against the validation profile
+//	dump      print the sheet as JSON, YAML or table
+//	fmt       rewrite the sheet in the canonical form (see cuefmt)
+//	convert   convert the sheet to or from other formats
+//	split     cut the sheet audio into one WAVE file per track
+//	discid    print CDDB and MusicBrainz disc IDs
+//
+// File "-" or no file means the standard input. Exit code is 0 on success,
+// 1 if the command failed (or found problems) and 2 on usage errors.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// command is the cuetool subcommand.
+type command struct {
+	summary string
+	run     func(env *env, args []string) int
+}
+
+var commands = map[string]command{
+	"validate": {"check the sheet against the validation profile", runValidate},
+	"dump":     {"print the sheet as JSON, YAML or table", runDump},
+	"fmt":      {"rewrite the sheet in the canonical form", runFmt},
+	"convert":  {"convert the sheet to or from other formats", runConvert},
+	"split":    {"cut the sheet audio into one WAVE file per track", runSplit},
+	"discid":   {"print CDDB and MusicBrainz disc IDs", runDiscID},
+}
+
+// env is the command environment.
+type env struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+}
+
+// errorf prints the error message prefixed with the program name.
+func (e *env) errorf(format string, args ...interface{}) {
+	fmt.Fprintf(e.stderr, "cuetool: "+format+"\n", args...)
+}
+
+// flagSet returns flag set of the command which prints errors to stderr.
+func (e *env) flagSet(name, args string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(e.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(e.stderr, "usage: cuetool %s [flags] %s\n", name, args)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// inputArg returns the only file argument of the command, "-" if omitted.
+func (e *env) inputArg(flags *flag.FlagSet) (string, bool) {
+	switch flags.NArg() {
+	case 0:
+		return "-", true
+	case 1:
+		return flags.Arg(0), true
+	}
+	flags.Usage()
+	return "", false
+}
+
+// open returns reader of the file or stdin for "-".
+func (e *env) open(name string) (io.ReadCloser, error) {
+	if name == "-" {
+		return io.NopCloser(e.stdin), nil
+	}
+	return os.Open(name)
+}
+
+// dir returns directory of the file, relative to which the sheet files are opened.
+func dir(name string) string {
+	if name == "-" {
+		return "."
+	}
+	return filepath.Dir(name)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: cuetool <command> [flags] [file]\n\ncommands:")
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-9s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w, "\nRun 'cuetool <command> -h' for the command flags.")
+}
+
+// run runs the command line and returns the exit code.
+func run(e *env, args []string) int {
+	if len(args) == 0 {
+		usage(e.stderr)
+		return exitUsage
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		usage(e.stdout)
+		return exitOK
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		e.errorf("unknown command %q", args[0])
+		usage(e.stderr)
+		return exitUsage
+	}
+	return cmd.run(e, args[1:])
+}
+
+func main() {
+	os.Exit(run(&env{os.Stdin, os.Stdout, os.Stderr}, os.Args[1:]))
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cue "github.com/tomoconnor/cue-go"
+)
+
+const testSheet = `PERFORMER "Band"
+TITLE "Album"
+FILE "album.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "One"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Two"
+    INDEX 00 00:01:00
+    INDEX 01 00:01:10
+`
+
+// runTest runs the command line and returns exit code, stdout and stderr.
+func runTest(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(&env{strings.NewReader(stdin), &stdout, &stderr}, args)
+	return code, stdout.String(), stderr.String()
+}
+
+// writeAlbum writes the test sheet and its 3 seconds WAVE file into the
+// temporary directory and returns the sheet path.
+func writeAlbum(t *testing.T) string {
+	dir := t.TempDir()
+	data := make([]byte, 3*44100*4)
+	header := make([]byte, 44)
+	copy(header, "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(36+len(data)))
+	copy(header[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1)
+	binary.LittleEndian.PutUint16(header[22:], 2)
+	binary.LittleEndian.PutUint32(header[24:], 44100)
+	binary.LittleEndian.PutUint32(header[28:], 44100*4)
+	binary.LittleEndian.PutUint16(header[32:], 4)
+	binary.LittleEndian.PutUint16(header[34:], 16)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], uint32(len(data)))
+
+	if err := os.WriteFile(filepath.Join(dir, "album.wav"), append(header, data...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(dir, "album.cue")
+	if err := os.WriteFile(name, []byte(testSheet), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestUsage(t *testing.T) {
+	if code, _, _ := runTest(""); code != exitUsage {
+		t.Fatalf("expected usage exit code, got %d", code)
+	}
+	if code, _, stderr := runTest("", "unknown"); code != exitUsage || !strings.Contains(stderr, "unknown command") {
+		t.Fatalf("unexpected result %d %q", code, stderr)
+	}
+	if code, stdout, _ := runTest("", "help"); code != exitOK || !strings.Contains(stdout, "discid") {
+		t.Fatalf("unexpected help %d %q", code, stdout)
+	}
+	if code, _, _ := runTest("", "dump", "-h"); code != exitOK {
+		t.Fatalf("unexpected command help exit code %d", code)
+	}
+	if code, _, _ := runTest("", "dump", "-format", "xml"); code != exitUsage {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	code, stdout, _ := runTest(testSheet, "validate")
+	if code != exitFailure || !strings.Contains(stdout, "-: error: track 1:") {
+		t.Fatalf("unexpected result %d %q", code, stdout)
+	}
+	if code, stdout, _ := runTest(testSheet, "validate", "-profile", "lenient-playback"); code != exitOK || stdout != "" {
+		t.Fatalf("unexpected result %d %q", code, stdout)
+	}
+	if code, _, stderr := runTest("TRACK", "validate"); code != exitFailure || stderr == "" {
+		t.Fatalf("unexpected result of the broken sheet %d %q", code, stderr)
+	}
+	if code, _, _ := runTest(testSheet, "validate", "-profile", "dvd"); code != exitUsage {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+
+	name := writeAlbum(t)
+	if code, _, stderr := runTest("", "validate", "-media", "-profile", "lenient-playback", name); code != exitOK {
+		t.Fatalf("unexpected result %d %q", code, stderr)
+	}
+}
+
+func TestDump(t *testing.T) {
+	code, stdout, _ := runTest(testSheet, "dump")
+	if code != exitOK || !strings.Contains(stdout, "02  AUDIO  00:01:10") {
+		t.Fatalf("unexpected table %d\n%s", code, stdout)
+	}
+
+	code, stdout, _ = runTest(testSheet, "dump", "-format", "yaml")
+	if code != exitOK || !strings.Contains(stdout, "\n      - number: 2\n        dataType: \"AUDIO\"\n        title: \"Two\"\n") {
+		t.Fatalf("unexpected YAML %d\n%s", code, stdout)
+	}
+
+	code, stdout, _ = runTest(testSheet, "dump", "-format", "json")
+	if code != exitOK {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+	code, roundTrip, _ := runTest(stdout, "convert", "-from", "json")
+	if code != exitOK || roundTrip != testSheet {
+		t.Fatalf("unexpected JSON round trip %d\n%s", code, roundTrip)
+	}
+}
+
+func TestFmt(t *testing.T) {
+	name := writeAlbum(t)
+	os.Remove(name)
+	if err := os.WriteFile(name, []byte(strings.Replace(testSheet, "  ", "\t", -1)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, stderr := runTest("", "fmt", "-w", name); code != exitOK {
+		t.Fatalf("unexpected result %d %q", code, stderr)
+	}
+	formatted := strings.NewReplacer(`"Band"`, "Band", `"Album"`, "Album", `"album.wav"`, "album.wav",
+		`"One"`, "One", `"Two"`, "Two").Replace(testSheet)
+	if data, _ := os.ReadFile(name); string(data) != formatted {
+		t.Fatalf("unexpected formatted sheet\n%s", data)
+	}
+	if info, err := os.Stat(name); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("file mode is not preserved %v", info.Mode())
+	}
+	if code, _, _ := runTest(testSheet, "fmt", "-w"); code != exitUsage {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	code, stdout, _ := runTest(testSheet, "convert", "-to", "youtube")
+	if code != exitOK || stdout != "0:00 One\n0:01 Two\n" {
+		t.Fatalf("unexpected result %d %q", code, stdout)
+	}
+
+	code, stdout, _ = runTest("0:00 One\n0:01.5 Two\n", "convert", "-from", "timestamps", "-media", "a.flac", "-type", "wave")
+	if code != exitOK || !strings.Contains(stdout, `FILE "a.flac" WAVE`) || !strings.Contains(stdout, "INDEX 01 00:01:38") {
+		t.Fatalf("unexpected result %d\n%s", code, stdout)
+	}
+
+	name := writeAlbum(t)
+	out := filepath.Join(filepath.Dir(name), "list", "album.m3u8")
+	os.Mkdir(filepath.Dir(out), 0755)
+	if code, _, stderr := runTest("", "convert", "-o", out, name); code != exitOK {
+		t.Fatalf("unexpected result %d %q", code, stderr)
+	}
+	if data, _ := os.ReadFile(out); !strings.Contains(string(data), "../album.wav") {
+		t.Fatalf("unexpected playlist\n%s", data)
+	}
+
+	if code, _, _ := runTest(testSheet, "convert", "-o", "album.doc"); code != exitUsage {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+
+	// JSON is converted without the cue sheet round trip.
+	const input = `{"files":[{"name":"a.wav","type":"WAVE","tracks":[{"id":"x1","number":1,"dataType":"AUDIO",` +
+		`"comments":["REPLAYGAIN_TRACK_GAIN -7.00 dB"],"indexes":[{"number":1,"time":"00:01:00"}]}]}]}`
+	code, stdout, _ = runTest(input, "convert", "-from", "json", "-to", "json")
+	if code != exitOK || !strings.Contains(stdout, `"id": "x1"`) || !strings.Contains(stdout, "REPLAYGAIN_TRACK_GAIN") ||
+		!strings.Contains(stdout, `"startPosition": 1`) {
+		t.Fatalf("unexpected result %d\n%s", code, stdout)
+	}
+
+	// Windows-1252 sheet.
+	code, stdout, _ = runTest(strings.Replace(testSheet, "Album", "Caf\xe9", 1), "convert", "-to", "json")
+	if code != exitOK || !strings.Contains(stdout, `"title": "Café"`) {
+		t.Fatalf("unexpected result %d\n%s", code, stdout)
+	}
+}
+
+func TestConvertEmbedded(t *testing.T) {
+	sheet, err := cue.Parse(strings.NewReader(testSheet), 3)
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+	name := writeAlbum(t)
+	wave, err := os.ReadFile(filepath.Join(filepath.Dir(name), "album.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tagged, ape bytes.Buffer
+	if err := cue.WriteWaveCues(&tagged, bytes.NewReader(wave), int64(len(wave)), sheet); err != nil {
+		t.Fatalf("Failed to write cues. %s", err.Error())
+	}
+	if err := cue.WriteAPECueSheet(&ape, bytes.NewReader(wave), int64(len(wave)), sheet); err != nil {
+		t.Fatalf("Failed to write APE tag. %s", err.Error())
+	}
+	for file, data := range map[string][]byte{"tagged.wav": tagged.Bytes(), "album.ape": ape.Bytes()} {
+		file = filepath.Join(filepath.Dir(name), file)
+		if err := os.WriteFile(file, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		code, stdout, stderr := runTest("", "convert", "-to", "cue", file)
+		if code != exitOK || !strings.Contains(stdout, "INDEX 01 00:01:10") {
+			t.Fatalf("%s: unexpected result %d %q\n%s", file, code, stderr, stdout)
+		}
+	}
+
+	// Input is read into memory.
+	code, stdout, stderr := runTest(tagged.String(), "convert", "-from", "wav", "-to", "cue")
+	if code != exitOK || !strings.Contains(stdout, "INDEX 01 00:01:10") {
+		t.Fatalf("unexpected result %d %q\n%s", code, stderr, stdout)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	name := writeAlbum(t)
+	out := filepath.Join(filepath.Dir(name), "tracks")
+	code, stdout, stderr := runTest("", "split", "-o", out, name)
+	if code != exitOK {
+		t.Fatalf("unexpected result %d %q", code, stderr)
+	}
+	if lines := strings.Split(strings.TrimSpace(stdout), "\n"); len(lines) != 2 {
+		t.Fatalf("unexpected output %q", stdout)
+	}
+	info, err := os.Stat(filepath.Join(out, "02 - Two.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Track 2 lasts from 00:01:10 up to the end of the file.
+	if size := int64(44 + (3*75-85)*588*4); info.Size() != size {
+		t.Fatalf("expected %d bytes track, got %d", size, info.Size())
+	}
+}
+
+func TestDiscID(t *testing.T) {
+	sheet, err := cue.Parse(strings.NewReader(testSheet), 3)
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+	cddb, _ := sheet.CDDBDiscID()
+	musicBrainz, _ := sheet.MusicBrainzDiscID()
+
+	name := writeAlbum(t)
+	code, stdout, stderr := runTest("", "discid", name)
+	if code != exitOK || stdout != "CDDB: "+cddb+"\nMusicBrainz: "+musicBrainz+"\n" {
+		t.Fatalf("unexpected result %d %q %q", code, stdout, stderr)
+	}
+	if code, stdout, _ := runTest("", "discid", "-id", "cddb", name); code != exitOK || stdout != cddb+"\n" {
+		t.Fatalf("unexpected result %d %q", code, stdout)
+	}
+	if code, _, _ := runTest(testSheet, "discid"); code != exitFailure {
+		t.Fatalf("unexpected exit code of unknown duration %d", code)
+	}
+
+	// Broken file of the sheet with the known duration.
+	data, err := json.Marshal(sheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name = filepath.Join(filepath.Dir(name), "album.json")
+	if err := os.WriteFile(name, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(filepath.Dir(name), "album.wav"), []byte("RIFF"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, stdout, stderr = runTest("", "discid", "-id", "cddb", name)
+	if code != exitOK || stdout != cddb+"\n" || !strings.Contains(stderr, "album.wav") {
+		t.Fatalf("unexpected result %d %q %q", code, stdout, stderr)
+	}
+}
@@ -0,0 +1,95 @@
+package cue
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ISRC is the International Standard Recording Code (ISO 3901).
+type ISRC struct {
+	// ISO 3166-1 alpha-2 country code.
+	Country string
+	// Registrant code, three alphanumeric characters.
+	Registrant string
+	// Last two digits of the reference year.
+	Year int
+	// Designation code.
+	Designation int
+}
+
+// String returns ISRC in the 12 characters form used by cue-sheets.
+func (isrc ISRC) String() string {
+	return fmt.Sprintf("%s%s%02d%05d", isrc.Country, isrc.Registrant, isrc.Year, isrc.Designation)
+}
+
+// ParseISRC parses ISRC in the compact (CCXXXYYNNNNN) or
+// hyphenated (CC-XXX-YY-NNNNN) form. Letters are case insensitive.
+func ParseISRC(s string) (isrc ISRC, err error) {
+	code := strings.ToUpper(s)
+	if len(code) == 15 && code[2] == '-' && code[6] == '-' && code[9] == '-' {
+		code = strings.Replace(code, "-", "", -1)
+	}
+	if len(code) != 12 {
+		return isrc, fmt.Errorf("%s is not valid ISRC, 12 characters expected", s)
+	}
+
+	for i := 0; i < 12; i++ {
+		c := code[i]
+		isLetter := c >= 'A' && c <= 'Z'
+		isDigit := c >= '0' && c <= '9'
+		switch {
+		case i < 2 && !isLetter:
+			return isrc, fmt.Errorf("%s is not valid ISRC, illegal country code", s)
+		case i >= 2 && i < 5 && !isLetter && !isDigit:
+			return isrc, fmt.Errorf("%s is not valid ISRC, illegal registrant code", s)
+		case i >= 5 && !isDigit:
+			return isrc, fmt.Errorf("%s is not valid ISRC, illegal year or designation code", s)
+		}
+	}
+
+	isrc.Country = code[:2]
+	isrc.Registrant = code[2:5]
+	isrc.Year, _ = strconv.Atoi(code[5:7])
+	isrc.Designation, _ = strconv.Atoi(code[7:])
+
+	return isrc, nil
+}
+
+// ValidateCatalog checks media catalog number: 13 digits EAN-13 code
+// (or UPC-A code with leading zero) with valid check digit.
+func ValidateCatalog(num string) error {
+	if err := checkCatalogDigits(num); err != nil {
+		return err
+	}
+
+	sum := 0
+	for i := 0; i < 12; i++ {
+		// Odd positions has weight 1, even positions has weight 3.
+		if i%2 == 0 {
+			sum += int(num[i] - '0')
+		} else {
+			sum += 3 * int(num[i]-'0')
+		}
+	}
+
+	if check := (10 - sum%10) % 10; int(num[12]-'0') != check {
+		return fmt.Errorf("%s is not valid catalog number, check digit should be %d", num, check)
+	}
+
+	return nil
+}
+
+// checkCatalogDigits checks media catalog number is 13 digits code,
+// the check digit is not verified.
+func checkCatalogDigits(num string) error {
+	if len(num) != 13 {
+		return fmt.Errorf("%s is not valid catalog number, 13 digits expected", num)
+	}
+	for i := 0; i < len(num); i++ {
+		if num[i] < '0' || num[i] > '9' {
+			return fmt.Errorf("%s is not valid catalog number, only digits allowed", num)
+		}
+	}
+	return nil
+}
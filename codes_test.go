@@ -0,0 +1,44 @@
+package cue
+
+import "testing"
+
+func TestParseISRC(t *testing.T) {
+	var tests = map[string]ISRC{
+		"USABC1234567":    {"US", "ABC", 12, 34567},
+		"gb-a1b-99-00001": {"GB", "A1B", 99, 1},
+	}
+
+	for input, expected := range tests {
+		isrc, err := ParseISRC(input)
+		if err != nil {
+			t.Fatalf("ISRC parsing failed, input string: '%s', error: %v", input, err)
+		}
+		if isrc != expected {
+			t.Fatalf("expected %v ISRC, but %v received.", expected, isrc)
+		}
+	}
+
+	for _, input := range []string{"USABC123456", "U1ABC1234567", "US[BC1234567", "USA_C1234567", "USABC12345A7"} {
+		if _, err := ParseISRC(input); err == nil {
+			t.Fatalf("invalid ISRC '%s' accepted", input)
+		}
+	}
+
+	if s := (ISRC{"US", "ABC", 2, 45}).String(); s != "USABC0200045" {
+		t.Fatalf("expected USABC0200045 but %s received", s)
+	}
+}
+
+func TestValidateCatalog(t *testing.T) {
+	for _, input := range []string{"4006381333931", "0036000291452", "0000000000000"} {
+		if err := ValidateCatalog(input); err != nil {
+			t.Fatalf("valid catalog number '%s' rejected: %v", input, err)
+		}
+	}
+
+	for _, input := range []string{"4006381333932", "036000291452", "40063813339a1"} {
+		if err := ValidateCatalog(input); err == nil {
+			t.Fatalf("invalid catalog number '%s' accepted", input)
+		}
+	}
+}
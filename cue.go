@@ -6,7 +6,6 @@ import (
 	"bufio"
 	"fmt"
 	"io"
-	"regexp"
 	"strconv"
 	"strings"
 	"unicode"
@@ -44,8 +43,29 @@ var parsersMap = map[string]commandParserDescriptor{
 }
 
 // Parse parses cue-sheet data (file) and returns filled Sheet struct.
+// Commands keywords, file types, flags and track datatypes are case-insensitive.
+// Combining marks are removed and TITLE, PERFORMER and SONGWRITER values
+// are truncated up to 80 characters.
 func Parse(reader io.Reader, durations ...float64) (sheet *Sheet, err error) {
-	sheet = new(Sheet)
+	sheet, err = parse(reader, true)
+	if err != nil {
+		return nil, err
+	}
+
+	for fi, f := range sheet.Files {
+		if len(durations) > fi {
+			f.Duration = durations[fi]
+		}
+	}
+	setPositions(sheet)
+
+	return sheet, nil
+}
+
+// parse parses cue-sheet data. Strings of the sheet which is not normalized
+// are kept as is, so Format doesn't change them.
+func parse(reader io.Reader, normalize bool) (*Sheet, error) {
+	sheet := new(Sheet)
 
 	rd := bufio.NewReader(reader)
 	lineNumber := 0
@@ -55,7 +75,10 @@ func Parse(reader io.Reader, durations ...float64) (sheet *Sheet, err error) {
 			return nil, err
 		}
 
-		line, _, _ := transform.String(runes.Remove(runes.In(unicode.Mn)), string(buf))
+		line := string(buf)
+		if normalize {
+			line, _, _ = transform.String(runes.Remove(runes.In(unicode.Mn)), line)
+		}
 		line = strings.TrimSpace(line)
 
 		// Skip empty lines.
@@ -70,6 +93,8 @@ func Parse(reader io.Reader, durations ...float64) (sheet *Sheet, err error) {
 			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
 		}
 
+		// Keywords are case-insensitive.
+		cmd = strings.ToUpper(cmd)
 		parserDescriptor, ok := parsersMap[cmd]
 		if !ok {
 			return nil, fmt.Errorf("line %d: unknown command '%s'", lineNumber, cmd)
@@ -88,36 +113,58 @@ func Parse(reader io.Reader, durations ...float64) (sheet *Sheet, err error) {
 		}
 	}
 
-	dLen := len(durations)
+	if normalize {
+		truncateStrings(sheet)
+	}
 
-	for fi, f := range sheet.Files {
-		if dLen > fi {
-			f.Duration = durations[fi]
+	return sheet, nil
+}
+
+// truncateStrings limits the sheet TITLE, PERFORMER and SONGWRITER values
+// length up to 80 characters.
+func truncateStrings(sheet *Sheet) {
+	values := []*string{&sheet.Performer, &sheet.Title, &sheet.Songwriter}
+	for _, f := range sheet.Files {
+		for _, t := range f.Tracks {
+			values = append(values, &t.Performer, &t.Title, &t.Songwriter)
 		}
+	}
+	for _, v := range values {
+		*v = stringTruncate(*v, 80)
+	}
+}
+
+// setPositions calculates tracks start and end positions (in seconds)
+// using files durations.
+func setPositions(sheet *Sheet) {
+	for _, f := range sheet.Files {
 		for ti, t := range f.Tracks {
 			t.StartPosition = t.StartTime().Seconds()
+			// PREGAP is not stored in the file, so the track lasts
+			// up to the next track INDEX 01.
 			var nextStart float64
 			if len(f.Tracks) > ti+1 {
-				nt := f.Tracks[ti+1]
-				if nextStart = nt.Pregap.Seconds(); nextStart == 0 {
-					nextStart = nt.StartTime().Seconds()
-				}
+				nextStart = f.Tracks[ti+1].StartTime().Seconds()
 			} else {
 				nextStart = f.Duration
 			}
 			t.EndPosition = nextStart
 		}
 	}
+}
 
-	return sheet, nil
+// UpdatePositions recalculates tracks start and end positions using
+// indexes and files durations, e.g. after the sheet is decoded from JSON.
+func (s *Sheet) UpdatePositions() {
+	setPositions(s)
 }
 
 // parseCatalog parsers CATALOG command.
+// Check digit is verified by Validate, many rips have wrong ones.
 func parseCatalog(params []string, sheet *Sheet) error {
 	num := params[0]
-	matched, _ := regexp.MatchString("^[0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9]$", num)
-	if !matched {
-		return fmt.Errorf("%s is not valid catalog number", params)
+	if err := checkCatalogDigits(num); err != nil {
+		return err
 	}
 	sheet.Catalog = num
 	return nil
@@ -133,63 +180,25 @@ func parseCdTextFile(params []string, sheet *Sheet) error {
 // params[0] -- fileName
 // params[1] -- fileType
 func parseFile(params []string, sheet *Sheet) error {
-	// Type parser function.
-	parseFileType := func(t string) (fileType FileType, err error) {
-		var types = map[string]FileType{
-			"BINARY":   FileTypeBinary,
-			"MOTOROLA": FileTypeMotorola,
-			"AIFF":     FileTypeAiff,
-			"WAVE":     FileTypeWave,
-			"MP3":      FileTypeMp3,
-		}
-
-		fileType, ok := types[t]
-		if !ok {
-			err = fmt.Errorf("unknown file type: %s", t)
-		}
-
-		return
-	}
-
-	fileType, err := parseFileType(params[1])
+	fileType, err := ParseFileType(strings.ToUpper(params[1]))
 	if err != nil {
 		return err
 	}
 
-	file := *new(File)
-	file.Name = params[0]
-	file.Type = fileType
-
-	sheet.Files = append(sheet.Files, &file)
+	sheet.AddFile(params[0], fileType)
 
 	return nil
 }
 
 // parseFlags parsers FLAGS command.
 func parseFlags(params []string, sheet *Sheet) error {
-	flagParser := func(flag string) (trackFlag TrackFlag, err error) {
-		var flags = map[string]TrackFlag{
-			"DCP":  TrackFlagDcp,
-			"4CH":  TrackFlag4ch,
-			"PRE":  TrackFlagPre,
-			"SCMS": TrackFlagScms,
-		}
-
-		trackFlag, ok := flags[flag]
-		if !ok {
-			err = fmt.Errorf("unknown track flag: %s", flag)
-		}
-
-		return
-	}
-
 	track := getCurrentTrack(sheet)
 	if track == nil {
 		return errors.New("TRACK command should appears before FLAGS command")
 	}
 
 	for _, flagStr := range params {
-		flag, err := flagParser(flagStr)
+		flag, err := ParseTrackFlag(strings.ToUpper(flagStr))
 		if err != nil {
 			return err
 		}
@@ -211,11 +220,6 @@ func parseIndex(params []string, sheet *Sheet) error {
 		return errors.Wrap(err, "failed to parse index number")
 	}
 
-	// All index numbers must be between 0 and 99 inclusive.
-	if number < 0 || number > 99 {
-		return errors.New("index number should be in 0..99 interval")
-	}
-
 	track := getCurrentTrack(sheet)
 	if track == nil {
 		return errors.New("TRACK command should appears before INDEX command")
@@ -228,24 +232,7 @@ func parseIndex(params []string, sheet *Sheet) error {
 	// 	}
 	// }
 
-	// This is the first track index?
-	if len(track.Indexes) == 0 {
-		// The first index must be 0 or 1.
-		if number >= 2 {
-			return errors.New("first track index should has 0 or 1 index number")
-		}
-	} else {
-		// All other indexes being sequential to the first one.
-		numberExpected := track.Indexes[len(track.Indexes)-1].Number + 1
-		if numberExpected != number {
-			return fmt.Errorf("expected %d index number but %d recieved", numberExpected, number)
-		}
-	}
-
-	index := Index{Number: number, Time: Time{min, sec, frames}}
-	track.Indexes = append(track.Indexes, index)
-
-	return nil
+	return appendIndex(track, Index{Number: number, Time: Time{min, sec, frames}})
 }
 
 // parseIsrc parsers ISRC command.
@@ -261,22 +248,19 @@ func parseIsrc(params []string, sheet *Sheet) error {
 		return errors.New("ISRC command must be specified before INDEX command")
 	}
 
-	re := "^[0-9a-zA-z][0-9a-zA-z][0-9a-zA-z][0-9a-zA-z][0-9a-zA-z]" +
-		"[0-9][0-9][0-9][0-9][0-9][0-9][0-9]$"
-	matched, _ := regexp.MatchString(re, isrc)
-	if !matched {
-		return fmt.Errorf("%s is not valid ISRC number", isrc)
+	code, err := ParseISRC(isrc)
+	if err != nil {
+		return err
 	}
 
-	track.Isrc = isrc
+	track.Isrc = code.String()
 
 	return nil
 }
 
 // parsePerformer parsers PERFORMER command.
 func parsePerformer(params []string, sheet *Sheet) error {
-	// Limit this field length up to 80 characters.
-	performer := stringTruncate(params[0], 80)
+	performer := params[0]
 	track := getCurrentTrack(sheet)
 
 	if track == nil {
@@ -329,16 +313,74 @@ func parsePregap(params []string, sheet *Sheet) error {
 }
 
 // parseRem parsers REM command.
+// REM SESSION, REM LEAD-IN and REM LEAD-OUT commands describe disc sessions,
+// all other REM commands are stored as comments of the current track, or
+// of the disc if they appear before the track.
 func parseRem(params []string, sheet *Sheet) error {
-	sheet.Comments = append(sheet.Comments, strings.Join(params, " "))
+	if len(params) == 2 {
+		switch strings.ToUpper(params[0]) {
+		case "SESSION":
+			// Other values are ordinary comments.
+			if number, err := strconv.Atoi(params[1]); err == nil && number == len(sheet.Sessions)+1 {
+				return parseSession(number, sheet)
+			}
+		case "LEAD-IN", "LEAD-OUT":
+			if len(sheet.Sessions) > 0 {
+				return parseSessionGap(params, sheet)
+			}
+		}
+	}
+
+	comment := strings.Join(params, " ")
+	if track := getCurrentTrack(sheet); track != nil {
+		track.Comments = append(track.Comments, comment)
+	} else {
+		sheet.Comments = append(sheet.Comments, comment)
+	}
+
+	return nil
+}
+
+// parseSession parsers REM SESSION command of the next session.
+func parseSession(number int, sheet *Sheet) error {
+	// Session starts with the next track.
+	firstTrack := 1
+	if track := getLastTrack(sheet); track != nil {
+		if number == 1 {
+			return errors.New("REM SESSION 01 must appear before any TRACK command")
+		}
+		firstTrack = track.Number + 1
+	}
+	if number > 1 && sheet.Sessions[number-2].FirstTrack == firstTrack {
+		return fmt.Errorf("session %d has no tracks", number-1)
+	}
+
+	sheet.Sessions = append(sheet.Sessions, Session{Number: number, FirstTrack: firstTrack})
+
+	return nil
+}
+
+// parseSessionGap parsers REM LEAD-IN and REM LEAD-OUT commands
+// of the current session.
+func parseSessionGap(params []string, sheet *Sheet) error {
+	min, sec, frames, err := parseTime(params[1])
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse %s time", strings.ToLower(params[0]))
+	}
+
+	session := &sheet.Sessions[len(sheet.Sessions)-1]
+	if strings.EqualFold(params[0], "LEAD-IN") {
+		session.LeadIn = Time{min, sec, frames}
+	} else {
+		session.LeadOut = Time{min, sec, frames}
+	}
 
 	return nil
 }
 
 // parseSongWriter parsers SONGWRITER command.
 func parseSongWriter(params []string, sheet *Sheet) error {
-	// Limit this field length up to 80 characters.
-	songwriter := stringTruncate(params[0], 80)
+	songwriter := params[0]
 	track := getCurrentTrack(sheet)
 
 	if track == nil {
@@ -352,8 +394,7 @@ func parseSongWriter(params []string, sheet *Sheet) error {
 
 // parseTitle parsers TITLE command.
 func parseTitle(params []string, sheet *Sheet) error {
-	// Limit this field length up to 80 characters.
-	title := stringTruncate(params[0], 80)
+	title := params[0]
 	track := getCurrentTrack(sheet)
 
 	if track == nil {
@@ -378,28 +419,6 @@ func parseTrack(params []string, sheet *Sheet) error {
 	numberStr := params[0]
 	dataTypeStr := params[1]
 
-	// Type parser function.
-	parseDataType := func(t string) (dataType TrackDataType, err error) {
-		var (
-			ok    bool
-			types = map[string]TrackDataType{
-				"AUDIO":      DataTypeAudio,
-				"CDG":        DataTypeCdg,
-				"MODE1/2048": DataTypeMode1_2048,
-				"MODE1/2352": DataTypeMode1_2352,
-				"MODE2/2336": DataTypeMode2_2336,
-				"MODE2/2352": DataTypeMode2_2352,
-				"CDI/2336":   DataTypeCdi_2336,
-				"CDI/2352":   DataTypeCdi_2352,
-			}
-		)
-
-		if dataType, ok = types[t]; !ok {
-			err = fmt.Errorf("unknown track datatype: %s", t)
-		}
-		return
-	}
-
 	number, err := strconv.Atoi(numberStr)
 	if err != nil {
 		return errors.Wrap(err, "failed to parse track number parameter")
@@ -408,7 +427,7 @@ func parseTrack(params []string, sheet *Sheet) error {
 		return errors.New("failed to parse track number parameter. value should be in 1..99 range")
 	}
 
-	dataType, err := parseDataType(dataTypeStr)
+	dataType, err := ParseDataType(strings.ToUpper(dataTypeStr))
 	if err != nil {
 		return err
 	}
@@ -453,6 +472,27 @@ func getCurrentTrack(sheet *Sheet) (t *Track) {
 	return
 }
 
+// getLastTrack returns the last track of the sheet, unlike getCurrentTrack
+// it skips files without tracks. Returns nil if there is no any Track object.
+func getLastTrack(sheet *Sheet) *Track {
+	for i := len(sheet.Files) - 1; i >= 0; i-- {
+		if tLen := len(sheet.Files[i].Tracks); tLen > 0 {
+			return sheet.Files[i].Tracks[tLen-1]
+		}
+	}
+	return nil
+}
+
+// hasStartIndex returns true if the track has INDEX 01 (or any later index).
+func hasStartIndex(track *Track) bool {
+	for _, idx := range track.Indexes {
+		if idx.Number > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // getFileLastIndex returns last index for the given file.
 // Returns nil if file has no any indexes.
 func getFileLastIndex(file *File) *Index {
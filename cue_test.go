@@ -2,6 +2,8 @@ package cue
 
 import (
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -19,3 +21,55 @@ func TestPackage(t *testing.T) {
 		t.Fatalf("Failed to parse file. %s", err.Error())
 	}
 }
+
+func TestParseCaseInsensitive(t *testing.T) {
+	const upper = `REM GENRE Rock
+TITLE "Album"
+FILE "album.bin" BINARY
+  TRACK 01 MODE1/2352
+    FLAGS DCP PRE
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    PREGAP 00:02:00
+    INDEX 01 10:00:00
+`
+	lower := strings.NewReplacer("REM GENRE", "Rem GENRE", "TITLE", "title", "FILE", "File", "BINARY", "binary",
+		"TRACK", "track", "MODE1", "mode1", "AUDIO", "Audio", "FLAGS", "flags", "DCP PRE", "dcp Pre",
+		"PREGAP", "pregap", "INDEX", "index").Replace(upper)
+
+	expected, err := Parse(strings.NewReader(upper))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+	sheet, err := Parse(strings.NewReader(lower))
+	if err != nil {
+		t.Fatalf("Failed to parse lower case sheet. %s", err.Error())
+	}
+	if !reflect.DeepEqual(sheet, expected) {
+		t.Fatalf("lower case sheet differs from the upper case one")
+	}
+}
+
+func TestParsePositions(t *testing.T) {
+	const input = `FILE "album.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    INDEX 00 03:00:00
+    INDEX 01 03:02:00
+  TRACK 03 AUDIO
+    PREGAP 00:02:00
+    INDEX 01 06:00:00
+`
+	sheet, err := Parse(strings.NewReader(input), 540)
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+	// INDEX 00 belongs to the previous track, PREGAP is not stored in the file.
+	expected := [][2]float64{{0, 182}, {182, 360}, {360, 540}}
+	for i, tr := range sheet.Files[0].Tracks {
+		if got := [2]float64{tr.StartPosition, tr.EndPosition}; got != expected[i] {
+			t.Fatalf("expected track %d positions %v, got %v", tr.Number, expected[i], got)
+		}
+	}
+}
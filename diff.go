@@ -0,0 +1,373 @@
+package cue
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Kind of the sheet change.
+type ChangeKind int
+
+const (
+	// Value or track is added.
+	ChangeAdded ChangeKind = iota
+	// Value or track is removed.
+	ChangeRemoved
+	// Value is changed.
+	ChangeModified
+	// Index is moved.
+	ChangeMoved
+)
+
+// changeKindNames contains change kinds names indexed by ChangeKind.
+var changeKindNames = []string{"added", "removed", "changed", "moved"}
+
+// String returns change kind name.
+func (k ChangeKind) String() string {
+	if k < 0 || int(k) >= len(changeKindNames) {
+		return fmt.Sprintf("ChangeKind(%d)", int(k))
+	}
+	return changeKindNames[k]
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (k ChangeKind) MarshalText() ([]byte, error) {
+	if k < 0 || int(k) >= len(changeKindNames) {
+		return nil, fmt.Errorf("unknown change kind: %d", int(k))
+	}
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (k *ChangeKind) UnmarshalText(text []byte) error {
+	for i, name := range changeKindNames {
+		if name == string(text) {
+			*k = ChangeKind(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown change kind: %s", text)
+}
+
+// Change is the difference between two sheets found by Diff.
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+	// Track number, 0 for disc level changes.
+	Track int `json:"track,omitempty"`
+	// Cue command keyword of the changed value: TITLE, ISRC, INDEX, etc.
+	// TRACK for added and removed tracks and changed datatypes.
+	Field string `json:"field"`
+	// Index number of the INDEX changes.
+	Index int `json:"index,omitempty"`
+	// Old and new values in the cue sheet syntax, empty if absent.
+	Old string `json:"old,omitempty"`
+	New string `json:"new,omitempty"`
+	// Index shift in frames of the moved index.
+	Frames int `json:"frames,omitempty"`
+}
+
+// String returns change description, e.g. "track 7: INDEX 01 moved by
+// +12 frames (04:00:00 -> 04:00:12)".
+func (c Change) String() string {
+	var s string
+	field := c.Field
+	if field == "INDEX" {
+		field = fmt.Sprintf("INDEX %02d", c.Index)
+	}
+
+	switch {
+	case c.Field == "TRACK" && c.Kind == ChangeAdded:
+		return fmt.Sprintf("track %d added (%s)", c.Track, c.New)
+	case c.Field == "TRACK" && c.Kind == ChangeRemoved:
+		return fmt.Sprintf("track %d removed (%s)", c.Track, c.Old)
+	case c.Kind == ChangeAdded:
+		s = fmt.Sprintf("%s added: %s", field, c.New)
+	case c.Kind == ChangeRemoved:
+		s = fmt.Sprintf("%s removed: %s", field, c.Old)
+	case c.Kind == ChangeMoved:
+		s = fmt.Sprintf("%s moved by %+d frames (%s -> %s)", field, c.Frames, c.Old, c.New)
+	default:
+		s = fmt.Sprintf("%s changed from %s to %s", field, c.Old, c.New)
+	}
+	if c.Track != 0 {
+		s = fmt.Sprintf("track %d: %s", c.Track, s)
+	}
+	return s
+}
+
+// diffField is the named value of the sheet or track.
+type diffField struct {
+	name, value string
+}
+
+// discFields returns disc values in the Write order, sessions values included.
+func discFields(s *Sheet) []diffField {
+	fields := []diffField{
+		{"CATALOG", s.Catalog},
+		{"CDTEXTFILE", diffString(s.CdTextFile)},
+		{"PERFORMER", diffString(s.Performer)},
+		{"TITLE", diffString(s.Title)},
+		{"SONGWRITER", diffString(s.Songwriter)},
+	}
+	for _, session := range s.Sessions {
+		name := fmt.Sprintf("REM SESSION %02d", session.Number)
+		fields = append(fields, diffField{name, fmt.Sprintf("TRACK %02d", session.FirstTrack)})
+		if session.LeadIn.TotalFrames() != 0 {
+			fields = append(fields, diffField{name + " LEAD-IN", session.LeadIn.String()})
+		}
+		if session.LeadOut.TotalFrames() != 0 {
+			fields = append(fields, diffField{name + " LEAD-OUT", session.LeadOut.String()})
+		}
+	}
+	return fields
+}
+
+// trackFields returns track values in the Write order.
+func trackFields(t *Track) []diffField {
+	flags := make([]string, len(t.Flags))
+	for i, f := range t.Flags {
+		flags[i] = f.String()
+	}
+	fields := []diffField{
+		{"TRACK", t.DataType.String()},
+		{"TITLE", diffString(t.Title)},
+		{"PERFORMER", diffString(t.Performer)},
+		{"SONGWRITER", diffString(t.Songwriter)},
+		{"FLAGS", strings.Join(flags, " ")},
+		{"ISRC", t.Isrc},
+	}
+	if t.Pregap.TotalFrames() != 0 {
+		fields = append(fields, diffField{"PREGAP", t.Pregap.String()})
+	}
+	if t.Postgap.TotalFrames() != 0 {
+		fields = append(fields, diffField{"POSTGAP", t.Postgap.String()})
+	}
+	return fields
+}
+
+// diffString returns string value as quoted parameter, empty if absent.
+func diffString(s string) string {
+	if s == "" {
+		return ""
+	}
+	return quote(s)
+}
+
+// diffFields appends changes of the named values.
+func diffFields(changes []Change, track int, a, b []diffField) []Change {
+	values := map[string]string{}
+	for _, f := range b {
+		values[f.name] = f.value
+	}
+	seen := map[string]bool{}
+	for _, f := range a {
+		seen[f.name] = true
+		if c, ok := diffValue(track, f.name, f.value, values[f.name]); ok {
+			changes = append(changes, c)
+		}
+	}
+	for _, f := range b {
+		if !seen[f.name] && f.value != "" {
+			changes = append(changes, Change{Kind: ChangeAdded, Track: track, Field: f.name, New: f.value})
+		}
+	}
+	return changes
+}
+
+// diffValue returns change of the value if any.
+func diffValue(track int, field, old, new string) (Change, bool) {
+	c := Change{Track: track, Field: field, Old: old, New: new}
+	switch {
+	case old == new:
+		return c, false
+	case old == "":
+		c.Kind = ChangeAdded
+	case new == "":
+		c.Kind = ChangeRemoved
+	default:
+		c.Kind = ChangeModified
+	}
+	return c, true
+}
+
+// trackPositions is the track with its indexes positions.
+type trackPositions struct {
+	track *Track
+	file  *File
+	// Index positions by index number.
+	indexes map[int]int
+}
+
+// sheetPositions returns tracks by numbers. With timeline, indexes
+// positions are counted on the sheet audio timeline, otherwise in the files.
+func sheetPositions(s *Sheet, timelined bool) map[int]trackPositions {
+	tracks := map[int]trackPositions{}
+	if timelined {
+		for _, sp := range timeline(s) {
+			tp := trackPositions{track: sp.track, file: sp.file, indexes: map[int]int{}}
+			for i, idx := range sp.track.Indexes {
+				tp.indexes[idx.Number] = sp.indexes[i]
+			}
+			tracks[sp.track.Number] = tp
+		}
+		return tracks
+	}
+
+	for _, f := range s.Files {
+		for _, t := range f.Tracks {
+			tp := trackPositions{track: t, file: f, indexes: map[int]int{}}
+			for _, idx := range t.Indexes {
+				tp.indexes[idx.Number] = idx.Time.TotalFrames()
+			}
+			tracks[t.Number] = tp
+		}
+	}
+	return tracks
+}
+
+// hasTimeline returns true if durations of all the files but last are known,
+// so tracks positions on the sheet audio timeline can be calculated.
+func hasTimeline(s *Sheet) bool {
+	for i, f := range s.Files {
+		if f.Duration == 0 && i < len(s.Files)-1 {
+			return false
+		}
+	}
+	return true
+}
+
+// Diff returns changes which turn the sheet a into the sheet b. Tracks
+// are matched by their numbers. If durations of the files of both sheets
+// are known (except the last files), indexes are compared by their
+// positions on the audio timeline made of all the sheet files joined, so
+// sheets of different files layouts (e.g. single-file sheet and its split
+// version) are equal. Otherwise indexes are compared within their files
+// and FILE changes of the tracks are reported.
+func Diff(a, b *Sheet) []Change {
+	changes := diffFields(nil, 0, discFields(a), discFields(b))
+
+	changes = diffComments(changes, 0, a.Comments, b.Comments)
+
+	timelined := hasTimeline(a) && hasTimeline(b)
+	aTracks, bTracks := sheetPositions(a, timelined), sheetPositions(b, timelined)
+	var numbers []int
+	for n := range aTracks {
+		numbers = append(numbers, n)
+	}
+	for n := range bTracks {
+		if _, ok := aTracks[n]; !ok {
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+
+	for _, n := range numbers {
+		at, inA := aTracks[n]
+		bt, inB := bTracks[n]
+		switch {
+		case !inA:
+			changes = append(changes, Change{Kind: ChangeAdded, Track: n, Field: "TRACK", New: bt.track.DataType.String()})
+			continue
+		case !inB:
+			changes = append(changes, Change{Kind: ChangeRemoved, Track: n, Field: "TRACK", Old: at.track.DataType.String()})
+			continue
+		}
+
+		changes = diffFields(changes, n, trackFields(at.track), trackFields(bt.track))
+		changes = diffComments(changes, n, at.track.Comments, bt.track.Comments)
+		if !timelined {
+			aFile := quote(at.file.Name) + " " + at.file.Type.String()
+			bFile := quote(bt.file.Name) + " " + bt.file.Type.String()
+			if c, ok := diffValue(n, "FILE", aFile, bFile); ok {
+				changes = append(changes, c)
+			}
+		}
+		changes = diffIndexes(changes, n, at, bt)
+	}
+
+	return changes
+}
+
+// diffComments appends changes of the comments compared as the sets of lines.
+func diffComments(changes []Change, track int, a, b []string) []Change {
+	count := map[string]int{}
+	for _, c := range a {
+		count[c]++
+	}
+	for _, c := range b {
+		count[c]--
+	}
+	for _, c := range a {
+		if count[c] > 0 {
+			count[c]--
+			changes = append(changes, Change{Kind: ChangeRemoved, Track: track, Field: "REM", Old: c})
+		}
+	}
+	for _, c := range b {
+		if count[c] < 0 {
+			count[c]++
+			changes = append(changes, Change{Kind: ChangeAdded, Track: track, Field: "REM", New: c})
+		}
+	}
+	return changes
+}
+
+// diffIndexes appends changes of the track indexes.
+func diffIndexes(changes []Change, track int, a, b trackPositions) []Change {
+	var numbers []int
+	for n := range a.indexes {
+		numbers = append(numbers, n)
+	}
+	for n := range b.indexes {
+		if _, ok := a.indexes[n]; !ok {
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+
+	for _, n := range numbers {
+		old, inA := a.indexes[n]
+		new, inB := b.indexes[n]
+		c := Change{Track: track, Field: "INDEX", Index: n}
+		switch {
+		case !inA:
+			c.Kind, c.New = ChangeAdded, TimeFromFrames(new).String()
+		case !inB:
+			c.Kind, c.Old = ChangeRemoved, TimeFromFrames(old).String()
+		case old != new:
+			c.Kind, c.Frames = ChangeMoved, new-old
+			c.Old, c.New = TimeFromFrames(old).String(), TimeFromFrames(new).String()
+		default:
+			continue
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+// WriteChanges writes changes as text, one change per line.
+func WriteChanges(w io.Writer, changes []Change) error {
+	var buf bytes.Buffer
+	for _, c := range changes {
+		fmt.Fprintln(&buf, c)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// WriteChangesJSON writes changes as JSON array.
+func WriteChangesJSON(w io.Writer, changes []Change) error {
+	if changes == nil {
+		changes = []Change{}
+	}
+	data, err := json.MarshalIndent(changes, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
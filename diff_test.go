@@ -0,0 +1,114 @@
+package cue
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const diffBase = `REM GENRE Rock
+PERFORMER "Band"
+TITLE "Album"
+FILE "album.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "One"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Two"
+    INDEX 00 03:00:00
+    INDEX 01 03:02:00
+  TRACK 03 AUDIO
+    TITLE "Three"
+    ISRC USABC9900001
+    INDEX 01 06:00:00
+`
+
+func TestDiff(t *testing.T) {
+	a, err := Parse(strings.NewReader(diffBase), 540)
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+	b, err := Parse(strings.NewReader(strings.NewReplacer(
+		"REM GENRE Rock", "REM GENRE Metal",
+		`TITLE "Two"`, `TITLE "Two"
+    ISRC USABC9900002`,
+		"INDEX 01 03:02:00", "INDEX 01 03:02:12",
+		`TITLE "Three"`, `TITLE "Tree"`,
+		"    ISRC USABC9900001\n", "",
+	).Replace(diffBase)+"  TRACK 04 AUDIO\n    INDEX 01 08:00:00\n"), 540)
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	var buf bytes.Buffer
+	if err := WriteChanges(&buf, Diff(a, b)); err != nil {
+		t.Fatalf("Failed to write changes. %s", err.Error())
+	}
+	expected := `REM removed: GENRE Rock
+REM added: GENRE Metal
+track 2: ISRC added: USABC9900002
+track 2: INDEX 01 moved by +12 frames (03:02:00 -> 03:02:12)
+track 3: TITLE changed from "Three" to "Tree"
+track 3: ISRC removed: USABC9900001
+track 4 added (AUDIO)
+`
+	if buf.String() != expected {
+		t.Fatalf("unexpected changes\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteChangesJSON(&buf, Diff(a, b)[3:4]); err != nil {
+		t.Fatalf("Failed to write changes. %s", err.Error())
+	}
+	var decoded []Change
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Failed to decode changes. %s", err.Error())
+	}
+	if !strings.Contains(buf.String(), `"kind": "moved"`) || !reflect.DeepEqual(decoded, Diff(a, b)[3:4]) {
+		t.Fatalf("unexpected JSON changes\n%s", buf.String())
+	}
+
+	if changes := Diff(a, a); len(changes) != 0 {
+		t.Fatalf("unexpected changes of the same sheet %v", changes)
+	}
+}
+
+func TestDiffLayouts(t *testing.T) {
+	single, err := Parse(strings.NewReader(diffBase), 540)
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+	name := func(t *Track) string { return t.Title + ".wav" }
+	for _, layout := range []GapLayout{GapsAppended, GapsPrepended} {
+		split, err := single.SplitFiles(Time{9, 0, 0}, layout, name)
+		if err != nil {
+			t.Fatalf("Failed to split sheet. %s", err.Error())
+		}
+		if changes := Diff(single, split); len(changes) != 0 {
+			t.Fatalf("unexpected changes of the split sheet %v", changes)
+		}
+	}
+
+	// Without durations indexes are compared within the files.
+	split, err := single.SplitFiles(Time{9, 0, 0}, GapsLeftOut, name)
+	if err != nil {
+		t.Fatalf("Failed to split sheet. %s", err.Error())
+	}
+	split.Files[0].Duration = 0
+	changes := Diff(single, split)
+	var lines []string
+	for _, c := range changes {
+		lines = append(lines, c.String())
+	}
+	expected := []string{
+		`track 2: PREGAP added: 00:02:00`,
+		`track 2: FILE changed from "album.wav" WAVE to "Two.wav" WAVE`,
+		`track 2: INDEX 00 removed: 03:00:00`,
+		`track 2: INDEX 01 moved by -13650 frames (03:02:00 -> 00:00:00)`,
+	}
+	if len(lines) != 7 || !reflect.DeepEqual(lines[1:5], expected) {
+		t.Fatalf("unexpected changes\n%s", strings.Join(lines, "\n"))
+	}
+}
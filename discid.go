@@ -0,0 +1,108 @@
+package cue
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// discTOC is the disc table of contents used for disc IDs calculation.
+type discTOC struct {
+	tracks []*Track
+	// Tracks INDEX 01 addresses (LBA + 150).
+	offsets []int
+	// Sessions lead-out addresses, the last one is the disc lead-out.
+	leadOuts []int
+}
+
+// discLayout returns tracks and lead-outs addresses on the disc. Virtual
+// gaps (PREGAP and POSTGAP) are counted, pregaps of the sessions first
+// tracks are treated as the standard 2 seconds pregaps. Sessions are
+// separated by their lead-outs and lead-ins. All the files durations
+// should be known.
+func discLayout(sheet *Sheet) (*discTOC, error) {
+	for _, f := range sheet.Files {
+		if f.Duration == 0 {
+			return nil, fmt.Errorf("duration of the file %s is unknown", f.Name)
+		}
+	}
+	spans := timeline(sheet)
+	if len(spans) == 0 {
+		return nil, errors.New("sheet has no tracks")
+	}
+
+	toc := new(discTOC)
+	extra := msfOffset
+	for i, sp := range spans {
+		t := sp.track
+		if i > 0 {
+			prev := spans[i-1].track
+			if session := sheet.TrackSession(t.Number); session != sheet.TrackSession(prev.Number) {
+				toc.leadOuts = append(toc.leadOuts, spans[i-1].end+extra)
+				extra += sheet.sessionGap(session)
+			} else {
+				extra += t.Pregap.TotalFrames()
+			}
+		}
+		toc.tracks = append(toc.tracks, t)
+		toc.offsets = append(toc.offsets, sp.start+extra)
+		extra += t.Postgap.TotalFrames()
+	}
+	toc.leadOuts = append(toc.leadOuts, spans[len(spans)-1].end+extra)
+
+	return toc, nil
+}
+
+// CDDBDiscID returns freedb (CDDB) disc ID. All the sessions tracks
+// are used, the disc lead-out is the last session lead-out.
+func (s *Sheet) CDDBDiscID() (string, error) {
+	toc, err := discLayout(s)
+	if err != nil {
+		return "", err
+	}
+
+	sum := 0
+	for _, offset := range toc.offsets {
+		for n := offset / framesPerSecond; n > 0; n /= 10 {
+			sum += n % 10
+		}
+	}
+	length := toc.leadOuts[len(toc.leadOuts)-1]/framesPerSecond - toc.offsets[0]/framesPerSecond
+	id := uint32(sum%0xff)<<24 | uint32(length)<<8 | uint32(len(toc.offsets))
+
+	return fmt.Sprintf("%08x", id), nil
+}
+
+// MusicBrainzDiscID returns MusicBrainz disc ID. Only the first session
+// is used, so the data session of Enhanced CD is skipped.
+func (s *Sheet) MusicBrainzDiscID() (string, error) {
+	toc, err := discLayout(s)
+	if err != nil {
+		return "", err
+	}
+
+	var offsets [99]int
+	first, last := toc.tracks[0].Number, 0
+	for i, t := range toc.tracks {
+		if s.TrackSession(t.Number) != 1 {
+			break
+		}
+		if t.Number < 1 || t.Number > len(offsets) {
+			return "", fmt.Errorf("illegal track number %d", t.Number)
+		}
+		offsets[t.Number-1] = toc.offsets[i]
+		last = t.Number
+	}
+
+	h := sha1.New()
+	fmt.Fprintf(h, "%02X%02X%08X", first, last, toc.leadOuts[0])
+	for _, offset := range offsets {
+		fmt.Fprintf(h, "%08X", offset)
+	}
+	id := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+	return strings.NewReplacer("+", ".", "/", "_", "=", "-").Replace(id), nil
+}
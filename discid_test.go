@@ -0,0 +1,85 @@
+package cue
+
+import (
+	"strings"
+	"testing"
+)
+
+const discIDSheet = `FILE "disc.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    INDEX 01 03:22:63
+  TRACK 03 AUDIO
+    INDEX 01 07:08:64
+  TRACK 04 AUDIO
+    INDEX 01 10:19:17
+  TRACK 05 AUDIO
+    INDEX 01 14:03:39
+  TRACK 06 AUDIO
+    INDEX 01 17:51:14
+`
+
+func TestDiscID(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(discIDSheet), 95312.0/framesPerSecond)
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	if id, err := sheet.CDDBDiscID(); err != nil || id != "3404f606" {
+		t.Fatalf("expected 3404f606 CDDB ID but %s received (%v)", id, err)
+	}
+	if id, err := sheet.MusicBrainzDiscID(); err != nil || id != "49HHV7Eb8UKF3aQiNmu1GR8vKTY-" {
+		t.Fatalf("expected 49HHV7Eb8UKF3aQiNmu1GR8vKTY- MusicBrainz ID but %s received (%v)", id, err)
+	}
+
+	sheet.Files[0].Duration = 0
+	if _, err := sheet.CDDBDiscID(); err == nil {
+		t.Fatalf("expected error for unknown file duration")
+	}
+}
+
+func TestDiscIDSessions(t *testing.T) {
+	audio, err := Parse(strings.NewReader(`FILE "audio.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    INDEX 01 04:00:00
+`), 480)
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+	enhanced, err := Parse(strings.NewReader(`REM SESSION 01
+FILE "audio.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    INDEX 01 04:00:00
+REM LEAD-OUT 01:30:00
+REM SESSION 02
+REM LEAD-IN 01:00:00
+FILE "data.bin" BINARY
+  TRACK 03 MODE2/2352
+    INDEX 01 00:00:00
+`), 480, 60)
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	expected, _ := audio.MusicBrainzDiscID()
+	if id, err := enhanced.MusicBrainzDiscID(); err != nil || id != expected {
+		t.Fatalf("expected %s MusicBrainz ID but %s received (%v)", expected, id, err)
+	}
+
+	toc, err := discLayout(enhanced)
+	if err != nil {
+		t.Fatalf("Failed to calculate layout. %s", err.Error())
+	}
+	// Audio session ends at 08:00:00, data track follows after 11400 frames.
+	if o := toc.offsets[2]; o != 36000+150+11400 {
+		t.Fatalf("expected data track at %d but %d received", 36000+150+11400, o)
+	}
+	if id, _ := enhanced.CDDBDiscID(); !strings.HasSuffix(id, "03") {
+		t.Fatalf("expected CDDB ID of 3 tracks but %s received", id)
+	}
+}
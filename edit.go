@@ -0,0 +1,371 @@
+package cue
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// AddFile appends new file to the sheet. Like the FILE command it moves
+// the last track, which has no INDEX 01 yet, into the new file (gaps
+// appended layout).
+func (s *Sheet) AddFile(name string, fileType FileType) *File {
+	file := &File{Name: name, Type: fileType}
+
+	if prev := getCurrentFile(s); prev != nil {
+		if tLen := len(prev.Tracks); tLen > 0 && !hasStartIndex(prev.Tracks[tLen-1]) {
+			track := prev.Tracks[tLen-1]
+			for i := range track.Indexes {
+				track.Indexes[i].InPreviousFile = true
+			}
+			prev.Tracks = prev.Tracks[:tLen-1]
+			file.Tracks = append(file.Tracks, track)
+		}
+	}
+
+	s.Files = append(s.Files, file)
+	setPositions(s)
+	return file
+}
+
+// AddTrack appends new track to the last file. The track is numbered
+// after the last sheet track and has no indexes.
+func (s *Sheet) AddTrack(dataType TrackDataType) (*Track, error) {
+	file := getCurrentFile(s)
+	if file == nil {
+		return nil, errors.New("sheet has no files, file expected before track")
+	}
+	number, err := s.nextTrackNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	track := &Track{Number: number, DataType: dataType}
+	file.Tracks = append(file.Tracks, track)
+	setPositions(s)
+	return track, nil
+}
+
+// InsertTrack inserts new track before the track with the given number,
+// so the new track gets this number and the following tracks are
+// renumbered. The track has no indexes, SetIndex sets its start.
+// Number following the last track appends the track like AddTrack.
+// The track inserted before the track started in the previous file (gaps
+// appended layout) is appended to the previous file.
+func (s *Sheet) InsertTrack(number int, dataType TrackDataType) (*Track, error) {
+	if last := getLastTrack(s); (last == nil && number == 1) || (last != nil && last.Number+1 == number) {
+		return s.AddTrack(dataType)
+	}
+	file, i, err := s.findTrack(number)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.nextTrackNumber(); err != nil {
+		return nil, err
+	}
+
+	s.renumber(number, 1)
+	// The new track starts the session of the track it precedes.
+	if ss := s.isSessionStart(number + 1); ss != nil {
+		ss.FirstTrack = number
+	}
+	track := &Track{Number: number, DataType: dataType}
+	if prev := s.previousFile(file); i == 0 && isContinued(file.Tracks[0]) && prev != nil {
+		prev.Tracks = append(prev.Tracks, track)
+		setPositions(s)
+		return track, nil
+	}
+	file.Tracks = append(file.Tracks[:i], append([]*Track{track}, file.Tracks[i:]...)...)
+	setPositions(s)
+	return track, nil
+}
+
+// RemoveTrack removes the track with the given number and renumbers
+// the following tracks. The file left without tracks is removed too, unless
+// it contains pregap of the next file track (gaps appended layout).
+func (s *Sheet) RemoveTrack(number int) error {
+	file, i, err := s.findTrack(number)
+	if err != nil {
+		return err
+	}
+	if ss := s.isSessionStart(number); ss != nil {
+		if _, _, err := s.findTrack(number + 1); err != nil || s.isSessionStart(number+1) != nil {
+			return fmt.Errorf("session %d would have no tracks", ss.Number)
+		}
+	}
+
+	prev := s.previousFile(file)
+	continued := isContinued(file.Tracks[i])
+	file.Tracks = append(file.Tracks[:i], file.Tracks[i+1:]...)
+	s.removeEmptyFile(file)
+	// The removed track pregap might be the only previous file content.
+	if continued && prev != nil {
+		s.removeEmptyFile(prev)
+	}
+	s.renumber(number+1, -1)
+	setPositions(s)
+	return nil
+}
+
+// SetIndex sets the time of the track index. Existing index is moved,
+// otherwise the index is appended to the track, so its number must follow
+// the last track index number (0 or 1 for the first index).
+func (s *Sheet) SetIndex(track, number int, time Time) error {
+	file, i, err := s.findTrack(track)
+	if err != nil {
+		return err
+	}
+	if err := checkTime(time); err != nil {
+		return err
+	}
+
+	t := file.Tracks[i]
+	for j := range t.Indexes {
+		if t.Indexes[j].Number == number {
+			t.Indexes[j].Time = time
+			setPositions(s)
+			return nil
+		}
+	}
+	if err := appendIndex(t, Index{Number: number, Time: time}); err != nil {
+		return errors.Wrapf(err, "track %d", track)
+	}
+	setPositions(s)
+	return nil
+}
+
+// SplitTrack splits the track with the given number into two tracks at
+// the given time of the track file and renumbers the following tracks.
+// The new track starts (INDEX 01) at the time, inherits the datatype,
+// flags, performer, songwriter and postgap of the split track and takes
+// its indexes located after the time.
+func (s *Sheet) SplitTrack(number int, at Time) (*Track, error) {
+	file, i, err := s.findTrack(number)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTime(at); err != nil {
+		return nil, err
+	}
+	if _, err := s.nextTrackNumber(); err != nil {
+		return nil, err
+	}
+
+	t := file.Tracks[i]
+	if !hasStartIndex(t) {
+		return nil, fmt.Errorf("track %d has no INDEX 01", number)
+	}
+	pos := at.TotalFrames()
+	if pos <= t.StartTime().TotalFrames() {
+		return nil, fmt.Errorf("split time %s should be after track %d start %s", at, number, t.StartTime())
+	}
+	if i+1 < len(file.Tracks) {
+		if next := file.Tracks[i+1]; len(next.Indexes) > 0 && pos >= next.Indexes[0].Time.TotalFrames() {
+			return nil, fmt.Errorf("split time %s should be before track %d start %s", at, next.Number, next.Indexes[0].Time)
+		}
+	} else if file.Duration > 0 && pos >= secondsToFrames(file.Duration) {
+		return nil, fmt.Errorf("split time %s should be before the file end", at)
+	}
+
+	// Indexes after the INDEX 01 located at or after the split time
+	// are moved to the new track.
+	split := len(t.Indexes)
+	for j, idx := range t.Indexes {
+		if idx.Number > 1 && idx.Time.TotalFrames() >= pos {
+			split = j
+			break
+		}
+	}
+	track := &Track{
+		Number:     number + 1,
+		DataType:   t.DataType,
+		Performer:  t.Performer,
+		Songwriter: t.Songwriter,
+		Flags:      append([]TrackFlag(nil), t.Flags...),
+		Indexes:    []Index{{Number: 1, Time: at}},
+		Postgap:    t.Postgap,
+	}
+	for _, idx := range t.Indexes[split:] {
+		if idx.Time != at {
+			track.Indexes = append(track.Indexes, Index{Number: len(track.Indexes) + 1, Time: idx.Time})
+		}
+	}
+	t.Indexes = t.Indexes[:split:split]
+	t.Postgap = Time{}
+
+	s.renumber(number+1, 1)
+	file.Tracks = append(file.Tracks[:i+1], append([]*Track{track}, file.Tracks[i+1:]...)...)
+	setPositions(s)
+	return track, nil
+}
+
+// MergeTracks merges the track with the given number and the next track
+// of the same file into one track and renumbers the following tracks.
+// The merged track keeps the first track information and takes the next
+// track indexes (except INDEX 00) and postgap.
+func (s *Sheet) MergeTracks(number int) error {
+	file, i, err := s.findTrack(number)
+	if err != nil {
+		return err
+	}
+	if i+1 >= len(file.Tracks) {
+		return fmt.Errorf("track %d is not followed by a track of the same file", number)
+	}
+	t, next := file.Tracks[i], file.Tracks[i+1]
+	if ss := s.isSessionStart(next.Number); ss != nil {
+		return fmt.Errorf("track %d starts session %d", next.Number, ss.Number)
+	}
+
+	indexes := append([]Index(nil), t.Indexes...)
+	for _, idx := range next.Indexes {
+		if l := len(indexes); l > 0 {
+			if idx.Number == 0 {
+				continue
+			}
+			idx.Number = indexes[l-1].Number + 1
+		}
+		if idx.Number > 99 {
+			return fmt.Errorf("merged track %d would have more than 99 indexes", number)
+		}
+		indexes = append(indexes, idx)
+	}
+	t.Indexes = indexes
+	t.Postgap = next.Postgap
+
+	file.Tracks = append(file.Tracks[:i+1], file.Tracks[i+2:]...)
+	s.renumber(next.Number+1, -1)
+	setPositions(s)
+	return nil
+}
+
+// ShiftIndexes moves all the sheet indexes by the given number of frames,
+// e.g. to apply the drive read offset. Indexes can't be moved before
+// the file start, the sheet is not modified in this case.
+func (s *Sheet) ShiftIndexes(frames int) error {
+	for _, f := range s.Files {
+		for _, t := range f.Tracks {
+			for _, idx := range t.Indexes {
+				if idx.Time.TotalFrames()+frames < 0 {
+					return fmt.Errorf("track %d INDEX %02d %s would be moved before the file start", t.Number, idx.Number, idx.Time)
+				}
+			}
+		}
+	}
+
+	for _, f := range s.Files {
+		for _, t := range f.Tracks {
+			for i := range t.Indexes {
+				t.Indexes[i].Time = TimeFromFrames(t.Indexes[i].Time.TotalFrames() + frames)
+			}
+		}
+	}
+	setPositions(s)
+	return nil
+}
+
+// findTrack returns the file containing track with the given number
+// and the track position in the file.
+func (s *Sheet) findTrack(number int) (*File, int, error) {
+	for _, f := range s.Files {
+		for i, t := range f.Tracks {
+			if t.Number == number {
+				return f, i, nil
+			}
+		}
+	}
+	return nil, 0, fmt.Errorf("track %d not found", number)
+}
+
+// previousFile returns the sheet file preceding the given one, nil for
+// the first file.
+func (s *Sheet) previousFile(file *File) *File {
+	for i := 1; i < len(s.Files); i++ {
+		if s.Files[i] == file {
+			return s.Files[i-1]
+		}
+	}
+	return nil
+}
+
+// removeEmptyFile removes the file if it has no tracks and the next file
+// doesn't start with the track continued from it.
+func (s *Sheet) removeEmptyFile(file *File) {
+	for i, f := range s.Files {
+		if f != file {
+			continue
+		}
+		if len(f.Tracks) > 0 || (i+1 < len(s.Files) && len(s.Files[i+1].Tracks) > 0 && isContinued(s.Files[i+1].Tracks[0])) {
+			return
+		}
+		s.Files = append(s.Files[:i], s.Files[i+1:]...)
+		return
+	}
+}
+
+// nextTrackNumber returns number following the last sheet track.
+func (s *Sheet) nextTrackNumber() (int, error) {
+	number := 1
+	if last := getLastTrack(s); last != nil {
+		number = last.Number + 1
+	}
+	if number > 99 {
+		return 0, errors.New("track number should be in 1..99 range")
+	}
+	return number, nil
+}
+
+// renumber adds delta to numbers of the tracks and sessions first tracks
+// starting from the given track number.
+func (s *Sheet) renumber(from, delta int) {
+	for _, f := range s.Files {
+		for _, t := range f.Tracks {
+			if t.Number >= from {
+				t.Number += delta
+			}
+		}
+	}
+	for i := range s.Sessions {
+		if s.Sessions[i].FirstTrack >= from {
+			s.Sessions[i].FirstTrack += delta
+		}
+	}
+}
+
+// appendIndex appends the index to the track. Index numbers must be
+// sequential starting from 0 or 1.
+func appendIndex(track *Track, index Index) error {
+	// All index numbers must be between 0 and 99 inclusive.
+	if index.Number < 0 || index.Number > 99 {
+		return errors.New("index number should be in 0..99 interval")
+	}
+
+	// This is the first track index?
+	if len(track.Indexes) == 0 {
+		// The first index must be 0 or 1.
+		if index.Number >= 2 {
+			return errors.New("first track index should has 0 or 1 index number")
+		}
+	} else {
+		// All other indexes being sequential to the first one.
+		numberExpected := track.Indexes[len(track.Indexes)-1].Number + 1
+		if numberExpected != index.Number {
+			return fmt.Errorf("expected %d index number but %d recieved", numberExpected, index.Number)
+		}
+	}
+
+	track.Indexes = append(track.Indexes, index)
+	return nil
+}
+
+// checkTime returns error if the time is not valid mm:ss:ff time point.
+func checkTime(time Time) error {
+	switch {
+	case time.Min < 0 || time.Sec < 0 || time.Frames < 0:
+		return fmt.Errorf("negative time %s", time)
+	case time.Sec > 59:
+		return errors.New("seconds value can't be more than 59")
+	case time.Frames > framesPerSecond-1:
+		return fmt.Errorf("frames value can't be more than %d", framesPerSecond-1)
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package cue
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// writeTest returns the sheet written by Write.
+func writeTest(t *testing.T, sheet *Sheet) string {
+	var buf bytes.Buffer
+	if err := Write(&buf, sheet); err != nil {
+		t.Fatalf("Failed to write sheet. %s", err.Error())
+	}
+	return buf.String()
+}
+
+func TestBuildSheet(t *testing.T) {
+	sheet := &Sheet{Title: "Album"}
+	if _, err := sheet.AddTrack(DataTypeAudio); err == nil {
+		t.Fatal("expected error of the track without file")
+	}
+	sheet.AddFile("album.wav", FileTypeWave)
+	for i := 0; i < 2; i++ {
+		if _, err := sheet.AddTrack(DataTypeAudio); err != nil {
+			t.Fatalf("Failed to add track. %s", err.Error())
+		}
+	}
+	steps := []error{
+		sheet.SetIndex(1, 1, Time{}),
+		sheet.SetIndex(2, 0, Time{3, 0, 0}),
+		sheet.SetIndex(2, 1, Time{3, 2, 0}),
+		// Index is moved.
+		sheet.SetIndex(2, 1, Time{3, 2, 10}),
+	}
+	for _, err := range steps {
+		if err != nil {
+			t.Fatalf("Failed to set index. %s", err.Error())
+		}
+	}
+
+	// Broken invariants.
+	for _, err := range []error{
+		sheet.SetIndex(1, 3, Time{1, 0, 0}),
+		sheet.SetIndex(3, 1, Time{}),
+		sheet.SetIndex(2, 100, Time{}),
+		sheet.SetIndex(2, 2, Time{4, 60, 0}),
+		sheet.SetIndex(2, 2, Time{4, 0, 75}),
+	} {
+		if err == nil {
+			t.Fatal("expected error of the invalid index")
+		}
+	}
+
+	// Track 2 pregap is in the first file.
+	sheet.Files[0].Tracks[1].Indexes = sheet.Files[0].Tracks[1].Indexes[:1]
+	sheet.AddFile("two.wav", FileTypeWave)
+	if len(sheet.Files[1].Tracks) != 1 || !sheet.Files[1].Tracks[0].Indexes[0].InPreviousFile {
+		t.Fatalf("expected track moved into the new file %+v", sheet.Files[1].Tracks)
+	}
+	if err := sheet.SetIndex(2, 1, Time{}); err != nil {
+		t.Fatalf("Failed to set index. %s", err.Error())
+	}
+
+	parsed, err := Parse(strings.NewReader(writeTest(t, sheet)))
+	if err != nil {
+		t.Fatalf("Failed to parse built sheet. %s", err.Error())
+	}
+	if writeTest(t, parsed) != writeTest(t, sheet) {
+		t.Fatalf("unexpected parsed sheet\n%s", writeTest(t, parsed))
+	}
+}
+
+const editBase = `FILE "album.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "One"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Two"
+    INDEX 00 03:00:00
+    INDEX 01 03:02:00
+    INDEX 02 04:00:00
+    INDEX 03 05:00:00
+  TRACK 03 AUDIO
+    TITLE "Three"
+    INDEX 01 06:00:00
+`
+
+// editSheet parses the edited sheet.
+func editSheet(t *testing.T) *Sheet {
+	sheet, err := Parse(strings.NewReader(editBase), 540)
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+	return sheet
+}
+
+func TestInsertRemoveTrack(t *testing.T) {
+	sheet := editSheet(t)
+	track, err := sheet.InsertTrack(2, DataTypeAudio)
+	if err != nil {
+		t.Fatalf("Failed to insert track. %s", err.Error())
+	}
+	track.Title = "Hidden"
+	if err := sheet.SetIndex(2, 1, Time{2, 0, 0}); err != nil {
+		t.Fatalf("Failed to set index. %s", err.Error())
+	}
+	expected := strings.NewReplacer(
+		"TRACK 03", "TRACK 04",
+		"TRACK 02", "TRACK 02 AUDIO\n    TITLE \"Hidden\"\n    INDEX 01 02:00:00\n  TRACK 03",
+	).Replace(editBase)
+	if s := writeTest(t, sheet); s != expected {
+		t.Fatalf("unexpected sheet\n%s", s)
+	}
+	if sheet.Files[0].Tracks[1].EndPosition != 182 {
+		t.Fatalf("positions are not updated %+v", sheet.Files[0].Tracks[1])
+	}
+
+	if err := sheet.RemoveTrack(2); err != nil {
+		t.Fatalf("Failed to remove track. %s", err.Error())
+	}
+	if s := writeTest(t, sheet); s != editBase {
+		t.Fatalf("unexpected sheet\n%s", s)
+	}
+	if err := sheet.RemoveTrack(4); err == nil {
+		t.Fatal("expected error of the unknown track")
+	}
+	if _, err := sheet.InsertTrack(5, DataTypeAudio); err == nil {
+		t.Fatal("expected error of the unknown track")
+	}
+	if track, err := sheet.InsertTrack(4, DataTypeAudio); err != nil || track.Number != 4 {
+		t.Fatalf("Failed to append track. %v", err)
+	}
+}
+
+func TestInsertTrackGapsAppended(t *testing.T) {
+	const input = `FILE "a.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    INDEX 00 03:00:00
+FILE "b.wav" WAVE
+    INDEX 01 00:00:00
+`
+	sheet, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+	// The new track is placed into the file of the preceding track.
+	if _, err := sheet.InsertTrack(2, DataTypeAudio); err != nil {
+		t.Fatalf("Failed to insert track. %s", err.Error())
+	}
+	if err := sheet.SetIndex(2, 1, Time{2, 0, 0}); err != nil {
+		t.Fatalf("Failed to set index. %s", err.Error())
+	}
+	expected := strings.Replace(input, "  TRACK 02", "  TRACK 02 AUDIO\n    INDEX 01 02:00:00\n  TRACK 03", 1)
+	if s := writeTest(t, sheet); s != expected {
+		t.Fatalf("unexpected sheet\n%s", s)
+	}
+}
+
+func TestRemoveTrackFiles(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(`FILE "a.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    INDEX 00 03:00:00
+FILE "b.wav" WAVE
+    INDEX 01 00:00:00
+FILE "c.wav" WAVE
+  TRACK 03 AUDIO
+    INDEX 01 00:00:00
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+	// The file keeps pregap of the next file track.
+	if err := sheet.RemoveTrack(1); err != nil {
+		t.Fatalf("Failed to remove track. %s", err.Error())
+	}
+	if len(sheet.Files) != 3 {
+		t.Fatalf("unexpected files\n%s", writeTest(t, sheet))
+	}
+	// Both files of the continued track are removed.
+	if err := sheet.RemoveTrack(1); err != nil {
+		t.Fatalf("Failed to remove track. %s", err.Error())
+	}
+	if s := writeTest(t, sheet); s != "FILE \"c.wav\" WAVE\n  TRACK 01 AUDIO\n    INDEX 01 00:00:00\n" {
+		t.Fatalf("unexpected sheet\n%s", s)
+	}
+}
+
+func TestEditSessions(t *testing.T) {
+	sheet := editSheet(t)
+	sheet.Sessions = []Session{{Number: 1, FirstTrack: 1}, {Number: 2, FirstTrack: 3}}
+
+	if _, err := sheet.InsertTrack(3, DataTypeMode1_2352); err != nil {
+		t.Fatalf("Failed to insert track. %s", err.Error())
+	}
+	if sheet.Sessions[1].FirstTrack != 3 {
+		t.Fatalf("expected the inserted track starting session 2, got %v", sheet.Sessions)
+	}
+	if err := sheet.RemoveTrack(3); err != nil || sheet.Sessions[1].FirstTrack != 3 {
+		t.Fatalf("unexpected removal result %v %v", err, sheet.Sessions)
+	}
+	if err := sheet.RemoveTrack(3); err == nil {
+		t.Fatal("expected error of the empty session")
+	}
+	if err := sheet.MergeTracks(2); err == nil {
+		t.Fatal("expected error of merging tracks of different sessions")
+	}
+	if _, err := sheet.SplitTrack(1, Time{1, 0, 0}); err != nil || sheet.Sessions[1].FirstTrack != 4 {
+		t.Fatalf("unexpected split result %v %v", err, sheet.Sessions)
+	}
+}
+
+func TestSplitMergeTracks(t *testing.T) {
+	sheet := editSheet(t)
+	sheet.Files[0].Tracks[1].Postgap = Time{0, 2, 0}
+	track, err := sheet.SplitTrack(2, Time{4, 0, 0})
+	if err != nil {
+		t.Fatalf("Failed to split track. %s", err.Error())
+	}
+	track.Title = "Two B"
+	expected := strings.NewReplacer(
+		"TRACK 03", "TRACK 04",
+		"    INDEX 02 04:00:00\n    INDEX 03 05:00:00\n",
+		"  TRACK 03 AUDIO\n    TITLE \"Two B\"\n    INDEX 01 04:00:00\n    INDEX 02 05:00:00\n    POSTGAP 00:02:00\n",
+	).Replace(editBase)
+	if s := writeTest(t, sheet); s != expected {
+		t.Fatalf("unexpected sheet\n%s", s)
+	}
+
+	if err := sheet.MergeTracks(2); err != nil {
+		t.Fatalf("Failed to merge tracks. %s", err.Error())
+	}
+	expected = strings.Replace(editBase, "    INDEX 03 05:00:00\n", "    INDEX 03 05:00:00\n    POSTGAP 00:02:00\n", 1)
+	if s := writeTest(t, sheet); s != expected {
+		t.Fatalf("unexpected sheet\n%s", s)
+	}
+
+	for _, at := range []Time{{3, 2, 0}, {1, 0, 0}, {6, 0, 0}, {7, 0, 0}} {
+		if _, err := sheet.SplitTrack(2, at); err == nil {
+			t.Fatalf("expected error of splitting at %s", at)
+		}
+	}
+	if _, err := sheet.SplitTrack(3, Time{9, 0, 0}); err == nil {
+		t.Fatal("expected error of splitting after the file end")
+	}
+	if err := sheet.MergeTracks(3); err == nil {
+		t.Fatal("expected error of merging the last track")
+	}
+}
+
+func TestShiftIndexes(t *testing.T) {
+	sheet := editSheet(t)
+	if err := sheet.ShiftIndexes(-1); err == nil {
+		t.Fatal("expected error of moving index before the file start")
+	}
+	if err := sheet.ShiftIndexes(30); err != nil {
+		t.Fatalf("Failed to shift indexes. %s", err.Error())
+	}
+	if err := sheet.ShiftIndexes(-10); err != nil {
+		t.Fatalf("Failed to shift indexes. %s", err.Error())
+	}
+	expected := strings.NewReplacer(":00:00\n", ":00:20\n", ":02:00\n", ":02:20\n").Replace(editBase)
+	if s := writeTest(t, sheet); s != expected {
+		t.Fatalf("unexpected sheet\n%s", s)
+	}
+	if track := sheet.Files[0].Tracks[2]; track.StartPosition != 360+20.0/75 {
+		t.Fatalf("positions are not updated %+v", track)
+	}
+}
@@ -0,0 +1,320 @@
+package cue
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// FLAC metadata blocks types.
+	flacStreamInfo = 0
+	flacPadding    = 1
+	flacCueSheet   = 5
+
+	// Lead-out track numbers of CD and non-CD FLAC cue-sheets.
+	flacLeadOutCD    = 170
+	flacLeadOutNonCD = 255
+	// Lead-in length of the CD in samples.
+	flacLeadIn = 88200
+)
+
+// flacBlock is the FLAC metadata block.
+type flacBlock struct {
+	typ  byte
+	data []byte
+}
+
+// readFLACMetadata reads FLAC stream marker and metadata blocks,
+// the reader is left at the first audio frame.
+func readFLACMetadata(r io.Reader) ([]flacBlock, error) {
+	marker := make([]byte, 4)
+	if _, err := io.ReadFull(r, marker); err != nil {
+		return nil, errors.Wrap(err, "failed to read FLAC stream marker")
+	}
+	if string(marker) != "fLaC" {
+		return nil, errors.New("not a FLAC stream")
+	}
+
+	var blocks []flacBlock
+	for last := false; !last; {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, errors.Wrap(err, "failed to read FLAC metadata block header")
+		}
+		last = header[0]&0x80 != 0
+		block := flacBlock{
+			typ:  header[0] & 0x7f,
+			data: make([]byte, int(header[1])<<16|int(header[2])<<8|int(header[3])),
+		}
+		if _, err := io.ReadFull(r, block.data); err != nil {
+			return nil, errors.Wrap(err, "failed to read FLAC metadata block")
+		}
+		blocks = append(blocks, block)
+	}
+
+	return blocks, nil
+}
+
+// writeFLACMetadata writes FLAC stream marker and metadata blocks.
+func writeFLACMetadata(w io.Writer, blocks []flacBlock) error {
+	var buf bytes.Buffer
+	buf.WriteString("fLaC")
+	for i, b := range blocks {
+		if len(b.data) >= 1<<24 {
+			return fmt.Errorf("FLAC metadata block of %d bytes is too large", len(b.data))
+		}
+		typ := b.typ
+		if i == len(blocks)-1 {
+			typ |= 0x80
+		}
+		l := len(b.data)
+		buf.Write([]byte{typ, byte(l >> 16), byte(l >> 8), byte(l)})
+		buf.Write(b.data)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// flacStreamParams returns sample rate and total samples from STREAMINFO block.
+func flacStreamParams(blocks []flacBlock) (sampleRate int, totalSamples uint64, err error) {
+	if len(blocks) == 0 || blocks[0].typ != flacStreamInfo || len(blocks[0].data) < 18 {
+		return 0, 0, errors.New("FLAC STREAMINFO block expected first")
+	}
+	b := blocks[0].data
+	sampleRate = int(b[10])<<12 | int(b[11])<<4 | int(b[12])>>4
+	totalSamples = uint64(b[13]&0x0f)<<32 | uint64(binary.BigEndian.Uint32(b[14:]))
+	return sampleRate, totalSamples, nil
+}
+
+// replaceFLACBlock returns metadata blocks with all blocks of the new block
+// type replaced by it. The new block is placed before padding.
+func replaceFLACBlock(blocks []flacBlock, block flacBlock) []flacBlock {
+	var out []flacBlock
+	inserted := false
+	for _, b := range blocks {
+		if b.typ == block.typ {
+			continue
+		}
+		if b.typ == flacPadding && !inserted {
+			out = append(out, block)
+			inserted = true
+		}
+		out = append(out, b)
+	}
+	if !inserted {
+		out = append(out, block)
+	}
+	return out
+}
+
+// DecodeFLACCueSheet converts FLAC CUESHEET metadata block into the sheet
+// with one WAVE file without name. Samples are converted into frames using
+// the stream sample rate, the lead-out track gives the file duration.
+// Non-audio tracks are decoded as MODE1/2352 tracks.
+func DecodeFLACCueSheet(data []byte, sampleRate int) (*Sheet, error) {
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("illegal sample rate %d", sampleRate)
+	}
+	r := bytes.NewReader(data)
+	read := func(v interface{}) error {
+		return binary.Read(r, binary.BigEndian, v)
+	}
+
+	var header struct {
+		Catalog  [128]byte
+		LeadIn   uint64
+		Flags    byte
+		Reserved [258]byte
+		Count    byte
+	}
+	if err := read(&header); err != nil {
+		return nil, errors.Wrap(err, "failed to read CUESHEET header")
+	}
+
+	sheet := new(Sheet)
+	sheet.Catalog = strings.TrimRight(string(header.Catalog[:]), "\x00")
+	file := &File{Type: FileTypeWave}
+	sheet.Files = []*File{file}
+
+	toTime := func(samples uint64) Time {
+		return TimeFromFrames(int(samples * framesPerSecond / uint64(sampleRate)))
+	}
+
+	for i := 0; i < int(header.Count); i++ {
+		var track struct {
+			Offset   uint64
+			Number   byte
+			Isrc     [12]byte
+			Flags    byte
+			Reserved [13]byte
+			Count    byte
+		}
+		if err := read(&track); err != nil {
+			return nil, errors.Wrap(err, "failed to read CUESHEET track")
+		}
+
+		if track.Number == flacLeadOutCD || track.Number == flacLeadOutNonCD {
+			file.Duration = float64(track.Offset) / float64(sampleRate)
+			break
+		}
+
+		t := &Track{Number: int(track.Number)}
+		t.Isrc = strings.TrimRight(string(track.Isrc[:]), "\x00")
+		if track.Flags&0x80 != 0 {
+			t.DataType = DataTypeMode1_2352
+		}
+		if track.Flags&0x40 != 0 {
+			t.Flags = append(t.Flags, TrackFlagPre)
+		}
+
+		for j := 0; j < int(track.Count); j++ {
+			var index struct {
+				Offset   uint64
+				Number   byte
+				Reserved [3]byte
+			}
+			if err := read(&index); err != nil {
+				return nil, errors.Wrap(err, "failed to read CUESHEET index")
+			}
+			t.Indexes = append(t.Indexes, Index{
+				Number: int(index.Number),
+				Time:   toTime(track.Offset + index.Offset),
+			})
+		}
+		file.Tracks = append(file.Tracks, t)
+	}
+	setPositions(sheet)
+
+	return sheet, nil
+}
+
+// EncodeFLACCueSheet converts single-file sheet into FLAC CUESHEET metadata
+// block. CD-DA cue-sheet (with lead-in and 170 lead-out track) is produced
+// for 44100 Hz streams.
+func EncodeFLACCueSheet(sheet *Sheet, sampleRate int, totalSamples uint64) ([]byte, error) {
+	if len(sheet.Files) != 1 {
+		return nil, fmt.Errorf("single file sheet expected, but %d files found", len(sheet.Files))
+	}
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("illegal sample rate %d", sampleRate)
+	}
+	isCD := sampleRate == cdFormat.sampleRate
+
+	var buf bytes.Buffer
+	write := func(v interface{}) {
+		binary.Write(&buf, binary.BigEndian, v)
+	}
+	toSamples := func(t Time) uint64 {
+		return uint64(t.TotalFrames()) * uint64(sampleRate) / framesPerSecond
+	}
+
+	var catalog [128]byte
+	copy(catalog[:], sheet.Catalog)
+	write(catalog)
+	if isCD {
+		write(uint64(flacLeadIn))
+		write(byte(0x80))
+	} else {
+		write(uint64(0))
+		write(byte(0))
+	}
+	write([258]byte{})
+
+	tracks := sheet.Files[0].Tracks
+	write(byte(len(tracks) + 1))
+	for _, t := range tracks {
+		if len(t.Indexes) == 0 {
+			return nil, fmt.Errorf("track %d has no indexes", t.Number)
+		}
+		if t.Pregap.TotalFrames() != 0 || t.Postgap.TotalFrames() != 0 {
+			return nil, fmt.Errorf("track %d: PREGAP and POSTGAP can't be stored in FLAC", t.Number)
+		}
+
+		offset := toSamples(t.Indexes[0].Time)
+		write(offset)
+		write(byte(t.Number))
+		var isrc [12]byte
+		copy(isrc[:], t.Isrc)
+		write(isrc)
+		var flags byte
+		if !t.DataType.IsAudio() {
+			flags |= 0x80
+		}
+		for _, f := range t.Flags {
+			if f == TrackFlagPre {
+				flags |= 0x40
+			}
+		}
+		write(flags)
+		write([13]byte{})
+		write(byte(len(t.Indexes)))
+		for _, idx := range t.Indexes {
+			if toSamples(idx.Time) < offset {
+				return nil, fmt.Errorf("track %d: INDEX %02d %s is before the track start %s", t.Number, idx.Number, idx.Time, t.Indexes[0].Time)
+			}
+			write(toSamples(idx.Time) - offset)
+			write(byte(idx.Number))
+			write([3]byte{})
+		}
+	}
+
+	// Lead-out track.
+	write(totalSamples)
+	if isCD {
+		write(byte(flacLeadOutCD))
+	} else {
+		write(byte(flacLeadOutNonCD))
+	}
+	write([12 + 1 + 13 + 1]byte{})
+
+	return buf.Bytes(), nil
+}
+
+// ReadFLACCueSheet reads sheet from the CUESHEET metadata block of the FLAC stream.
+func ReadFLACCueSheet(r io.Reader) (*Sheet, error) {
+	blocks, err := readFLACMetadata(r)
+	if err != nil {
+		return nil, err
+	}
+	sampleRate, _, err := flacStreamParams(blocks)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range blocks {
+		if b.typ == flacCueSheet {
+			return DecodeFLACCueSheet(b.data, sampleRate)
+		}
+	}
+
+	return nil, errors.New("FLAC stream has no CUESHEET block")
+}
+
+// WriteFLACCueSheet copies FLAC stream from r to w replacing its CUESHEET
+// metadata block with the sheet. Audio frames are not re-encoded.
+func WriteFLACCueSheet(w io.Writer, r io.Reader, sheet *Sheet) error {
+	blocks, err := readFLACMetadata(r)
+	if err != nil {
+		return err
+	}
+	sampleRate, totalSamples, err := flacStreamParams(blocks)
+	if err != nil {
+		return err
+	}
+	data, err := EncodeFLACCueSheet(sheet, sampleRate, totalSamples)
+	if err != nil {
+		return err
+	}
+
+	blocks = replaceFLACBlock(blocks, flacBlock{flacCueSheet, data})
+	if err := writeFLACMetadata(w, blocks); err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
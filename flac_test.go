@@ -0,0 +1,97 @@
+package cue
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// streamInfo returns FLAC STREAMINFO metadata block.
+func streamInfo(sampleRate int, totalSamples uint64) flacBlock {
+	info := make([]byte, 34)
+	info[10] = byte(sampleRate >> 12)
+	info[11] = byte(sampleRate >> 4)
+	info[12] = byte(sampleRate<<4) | 0x02
+	info[13] = 0xf0 | byte(totalSamples>>32)
+	binary.BigEndian.PutUint32(info[14:], uint32(totalSamples))
+	return flacBlock{flacStreamInfo, info}
+}
+
+// flacStream returns FLAC stream with STREAMINFO, PADDING and fake audio frames.
+func flacStream(sampleRate int, totalSamples uint64, audio []byte) []byte {
+	var buf bytes.Buffer
+	writeFLACMetadata(&buf, []flacBlock{streamInfo(sampleRate, totalSamples), {flacPadding, make([]byte, 16)}})
+	buf.Write(audio)
+	return buf.Bytes()
+}
+
+func TestFLACCueSheet(t *testing.T) {
+	file, err := os.Open("test.cue")
+	if err != nil {
+		t.Fatalf("Failed to open file. %s", err.Error())
+	}
+	defer file.Close()
+	sheet, err := Parse(file, 40*60)
+	if err != nil {
+		t.Fatalf("Failed to parse file. %s", err.Error())
+	}
+	sheet.Catalog = "4006381333931"
+	sheet.Files[0].Tracks[1].Isrc = "USABC1234567"
+	sheet.Files[0].Tracks[2].Flags = []TrackFlag{TrackFlagPre}
+	sheet.Files[0].Tracks[3].Indexes = []Index{{0, Time{11, 46, 3}, false}, {1, Time{11, 48, 3}, false}}
+
+	audio := []byte("audio frames")
+	stream := flacStream(44100, 40*60*44100, audio)
+	var out bytes.Buffer
+	if err := WriteFLACCueSheet(&out, bytes.NewReader(stream), sheet); err != nil {
+		t.Fatalf("Failed to write CUESHEET. %s", err.Error())
+	}
+	if !bytes.HasSuffix(out.Bytes(), audio) {
+		t.Fatalf("audio frames are not preserved")
+	}
+
+	blocks, err := readFLACMetadata(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("Failed to read metadata. %s", err.Error())
+	}
+	var types []byte
+	for _, b := range blocks {
+		types = append(types, b.typ)
+	}
+	if !bytes.Equal(types, []byte{flacStreamInfo, flacCueSheet, flacPadding}) {
+		t.Fatalf("unexpected metadata blocks %v", types)
+	}
+
+	decoded, err := ReadFLACCueSheet(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("Failed to read CUESHEET. %s", err.Error())
+	}
+	if decoded.Catalog != sheet.Catalog || decoded.Files[0].Duration != 40*60 {
+		t.Fatalf("unexpected disc information %+v", decoded)
+	}
+	for i, tr := range decoded.Files[0].Tracks {
+		expected := sheet.Files[0].Tracks[i]
+		if tr.Number != expected.Number || tr.Isrc != expected.Isrc ||
+			!reflect.DeepEqual(tr.Indexes, expected.Indexes) || len(tr.Flags) != len(expected.Flags) {
+			t.Fatalf("track %d: expected %+v but %+v decoded", expected.Number, expected, tr)
+		}
+	}
+
+	// Decoded sheet should be encoded in the same way.
+	data, _ := EncodeFLACCueSheet(sheet, 44100, 40*60*44100)
+	again, _ := EncodeFLACCueSheet(decoded, 44100, 40*60*44100)
+	if !bytes.Equal(data, again) {
+		t.Fatalf("decoded CUESHEET is encoded differently")
+	}
+	if data[128+8] != 0x80 || data[len(data)-28] != flacLeadOutCD {
+		t.Fatalf("CD-DA CUESHEET expected")
+	}
+
+	// Index before the track first index.
+	sheet.Files[0].Tracks[3].Indexes[1].Time = Time{11, 40, 0}
+	if _, err := EncodeFLACCueSheet(sheet, 44100, 40*60*44100); err == nil {
+		t.Fatal("expected error of the index before the track start")
+	}
+}
@@ -0,0 +1,48 @@
+package cue
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+)
+
+// Format parses the cue sheet and returns it in the canonical layout:
+// UTF-8 text with LF line endings, upper case keywords, disc commands
+// before the files, FILE, TRACK and INDEX blocks indented by two spaces,
+// zero-padded track and index numbers and strings quoted only if needed.
+// UTF-16 and Windows-1252 sheets are converted into UTF-8. Unlike Parse,
+// strings are kept as is: combining marks are not removed and long values
+// are not truncated.
+func Format(src []byte) ([]byte, error) {
+	text, err := DecodeText(src)
+	if err != nil {
+		return nil, err
+	}
+	sheet, err := parse(strings.NewReader(text), false)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := writeSheet(&buf, sheet, quoteIfNeeded); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// quoteIfNeeded returns string parameter as is if parseCommand reads it back
+// unquoted, or wrapped with quotes otherwise.
+func quoteIfNeeded(s string) string {
+	if s == "" {
+		return quote(s)
+	}
+	for i := 0; i < len(s); i++ {
+		// Parameters are split by bytes, so UTF-8 continuation bytes
+		// like 0x85 and 0xa0 are separators too.
+		c := s[i]
+		if unicode.IsSpace(rune(c)) || isQuoteChar(c) || c == '\\' || c < ' ' || c == 0x7f {
+			return quote(s)
+		}
+	}
+	return s
+}
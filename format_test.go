@@ -0,0 +1,142 @@
+package cue
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+const formattedSheet = `REM GENRE Rock
+CATALOG 1234567890128
+PERFORMER "The Band"
+TITLE Café
+FILE "01 Intro.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "Say \"Hi\""
+    PERFORMER "à"
+    FLAGS DCP PRE
+    INDEX 01 00:00:00
+FILE track2.wav WAVE
+  TRACK 02 AUDIO
+    TITLE "C:\\Temp"
+    INDEX 00 00:00:00
+    INDEX 01 00:02:00
+`
+
+func TestFormat(t *testing.T) {
+	messy := "\ufefftitle 'Café'\r\n" +
+		"\t performer   \"The Band\"\r\n" +
+		"Rem GENRE Rock\r\n" +
+		"catalog 1234567890128\r\n" +
+		"file '01 Intro.wav' wave\r\n" +
+		"track 1 audio\r\n" +
+		"      index 1 00:00:00\r\n" +
+		"   flags dcp pre\r\n" +
+		"TITLE \"Say \\\"Hi\\\"\"\n" +
+		"PERFORMER \"à\"\n" +
+		"FILE \"track2.wav\" WAVE\n" +
+		"  TRACK 2 AUDIO\n" +
+		"    TITLE C:\\\\Temp\n" +
+		"    INDEX 0 00:00:00\n" +
+		"    INDEX 1 00:02:00\n"
+
+	formatted, err := Format([]byte(messy))
+	if err != nil {
+		t.Fatalf("Failed to format sheet. %s", err.Error())
+	}
+	if string(formatted) != formattedSheet {
+		t.Fatalf("unexpected formatted sheet\n%s", formatted)
+	}
+
+	again, err := Format(formatted)
+	if err != nil || string(again) != string(formatted) {
+		t.Fatalf("formatting is not idempotent (%v)\n%s", err, again)
+	}
+
+	// Windows-1252 sheet.
+	cp1252, _ := charmap.Windows1252.NewEncoder().String(formattedSheet)
+	if formatted, err := Format([]byte(cp1252)); err != nil || string(formatted) != formattedSheet {
+		t.Fatalf("unexpected formatted Windows-1252 sheet (%v)\n%s", err, formatted)
+	}
+
+	if _, err := Format([]byte("TRACK 01 AUDIO")); err == nil {
+		t.Fatalf("broken sheet is formatted")
+	}
+}
+
+func TestFormatTrackComments(t *testing.T) {
+	const sheet = `REM REPLAYGAIN_ALBUM_GAIN -5.00 dB
+TITLE Album
+FILE album.wav WAVE
+  TRACK 01 AUDIO
+    TITLE One
+    REM REPLAYGAIN_TRACK_GAIN -7.00 dB
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE Two
+    REM REPLAYGAIN_TRACK_GAIN -3.00 dB
+    REM REPLAYGAIN_TRACK_PEAK 0.988525
+    INDEX 01 03:00:00
+`
+	// EAC writes track comments after INDEX commands.
+	messy := strings.NewReplacer(
+		"    REM REPLAYGAIN_TRACK_GAIN -7.00 dB\n    INDEX 01 00:00:00\n",
+		"    INDEX 01 00:00:00\n    REM REPLAYGAIN_TRACK_GAIN -7.00 dB\n",
+	).Replace(sheet)
+
+	formatted, err := Format([]byte(messy))
+	if err != nil {
+		t.Fatalf("Failed to format sheet. %s", err.Error())
+	}
+	if string(formatted) != sheet {
+		t.Fatalf("unexpected formatted sheet\n%s", formatted)
+	}
+}
+
+func TestFormatStrings(t *testing.T) {
+	// Decomposed "é" and 90 bytes long title.
+	const title = "Cafe\u0301 " + "Ωμέγα Ωμέγα Ωμέγα Ωμέγα Ωμέγα Ωμέγα Ωμέγα Ωμέγα"
+	if len(title) <= 80 {
+		t.Fatalf("title should be longer than 80 bytes, got %d", len(title))
+	}
+	sheet := "TITLE \"" + title + "\"\nFILE album.wav WAVE\n  TRACK 01 AUDIO\n    PERFORMER \"" + title + "\"\n    INDEX 01 00:00:00\n"
+
+	formatted, err := Format([]byte(sheet))
+	if err != nil || string(formatted) != sheet {
+		t.Fatalf("unexpected formatted sheet (%v)\n%s", err, formatted)
+	}
+	again, err := Format(formatted)
+	if err != nil || string(again) != sheet {
+		t.Fatalf("formatting is not idempotent (%v)\n%s", err, again)
+	}
+
+	// Parse still normalizes strings.
+	parsed, err := Parse(strings.NewReader(sheet))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+	if p := parsed.Files[0].Tracks[0].Performer; len(p) != 80 || strings.ContainsRune(p, '\u0301') {
+		t.Fatalf("unexpected parsed performer %q", p)
+	}
+}
+
+func TestQuoteIfNeeded(t *testing.T) {
+	var tests = []struct {
+		input, expected string
+	}{
+		{"Plain", "Plain"},
+		{"", `""`},
+		{"Two words", `"Two words"`},
+		{"It's", `"It's"`},
+		{`a\b`, `"a\\b"`},
+		// 0xa0 byte of the UTF-8 sequence is a space for the parser.
+		{"à", `"à"`},
+		{"é", "é"},
+	}
+	for _, test := range tests {
+		if got := quoteIfNeeded(test.input); got != test.expected {
+			t.Fatalf("expected %s for %q, got %s", test.expected, test.input, got)
+		}
+	}
+}
@@ -1,6 +1,6 @@
 module github.com/tomoconnor/cue-go
 
-go 1.12
+go 1.17
 
 require (
 	github.com/pkg/errors v0.8.1
@@ -0,0 +1,114 @@
+package cue
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// trackInfo is the track description served by the tracks list.
+type trackInfo struct {
+	Number    int     `json:"number"`
+	Title     string  `json:"title,omitempty"`
+	Performer string  `json:"performer,omitempty"`
+	Duration  float64 `json:"duration"`
+	Size      int64   `json:"size"`
+	URL       string  `json:"url"`
+}
+
+// Handler is http.Handler which serves the sheet tracks cut from the image:
+//
+//	GET /               JSON list of the tracks
+//	GET /track/N.wav    track N as WAVE file, byte ranges are supported
+type Handler struct {
+	sheet *Sheet
+	tfs   *TrackFS
+}
+
+// NewHandler returns handler of the sheet tracks. src contains files
+// referenced by the sheet, see NewTrackFS.
+func NewHandler(sheet *Sheet, src fs.FS) (*Handler, error) {
+	tfs, err := NewTrackFS(sheet, src)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{sheet: sheet, tfs: tfs}, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	p := path.Clean("/" + r.URL.Path)
+	if p == "/" {
+		h.serveList(w)
+		return
+	}
+
+	dir, name := path.Split(p)
+	if dir != "/track/" || !strings.HasSuffix(name, ".wav") {
+		http.NotFound(w, r)
+		return
+	}
+	number, err := strconv.Atoi(strings.TrimSuffix(name, ".wav"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	e := h.entry(number)
+	if e == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := h.tfs.Open(e.name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "audio/wav")
+	http.ServeContent(w, r, e.name, e.modTime, f.(*TrackFile))
+}
+
+// serveList writes JSON list of the served tracks.
+func (h *Handler) serveList(w http.ResponseWriter) {
+	list := make([]trackInfo, 0, len(h.tfs.entries))
+	for _, f := range h.sheet.Files {
+		for _, t := range f.Tracks {
+			e := h.entry(t.Number)
+			if e == nil {
+				continue
+			}
+			list = append(list, trackInfo{
+				Number:    t.Number,
+				Title:     t.Title,
+				Performer: t.Performer,
+				Duration:  float64(e.size) / float64(e.format.sampleRate*e.format.blockAlign),
+				Size:      e.Size(),
+				URL:       "/track/" + strconv.Itoa(t.Number) + ".wav",
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// entry returns virtual file of the track with the given number.
+func (h *Handler) entry(number int) *trackEntry {
+	for _, e := range h.tfs.entries {
+		if e.track.Number == number {
+			return e
+		}
+	}
+	return nil
+}
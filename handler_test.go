@@ -0,0 +1,68 @@
+package cue
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestHandler(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(`FILE "image.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "One"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Two"
+    INDEX 01 00:00:50
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	audio := sampleBytes(75 * 2352)
+	h, err := NewHandler(sheet, fstest.MapFS{
+		"image.wav": {Data: append(cdFormat.header(int64(len(audio))), audio...)},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create handler. %s", err.Error())
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	var list []trackInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to decode tracks list. %s", err.Error())
+	}
+	if len(list) != 2 || list[1].Title != "Two" || list[1].URL != "/track/2.wav" {
+		t.Fatalf("unexpected tracks list %+v", list)
+	}
+	if d := list[0].Duration; d != 50.0/75 {
+		t.Fatalf("expected %f duration but %f received", 50.0/75, d)
+	}
+
+	req := httptest.NewRequest("GET", "/track/2.wav", nil)
+	req.Header.Set("Range", "bytes=44-51")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("expected %d status but %d received", http.StatusPartialContent, rec.Code)
+	}
+	start := 50 * 2352
+	if body := rec.Body.String(); body != string(audio[start:start+8]) {
+		t.Fatalf("unexpected range data % x", body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "audio/wav" {
+		t.Fatalf("unexpected content type %s", ct)
+	}
+
+	for _, p := range []string{"/track/3.wav", "/track/one.wav", "/tracks"} {
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", p, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected %d status but %d received", p, http.StatusNotFound, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package cue
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// isoSectorSize is the size of the ISO 9660 image sector.
+const isoSectorSize = 2048
+
+// FirstDataTrack returns the first data track of the sheet and its file.
+// Returns nils if the sheet has no data tracks.
+func (s *Sheet) FirstDataTrack() (*File, *Track) {
+	for _, f := range s.Files {
+		for _, t := range f.Tracks {
+			if !t.DataType.IsAudio() {
+				return f, t
+			}
+		}
+	}
+	return nil, nil
+}
+
+// ExtractISO writes the first data track of the binary file f as plain
+// ISO 9660 image with 2048 bytes sectors. r and size describe the file data.
+// MODE1 and MODE2 form 1 sources are supported, MODE2 form 2 sectors
+// can't be represented in ISO image, so error is returned for them.
+func ExtractISO(w io.Writer, r io.ReaderAt, size int64, f *File) error {
+	extents, err := f.Extents(size)
+	if err != nil {
+		return err
+	}
+
+	var extent *Extent
+	for i := range extents {
+		if !extents[i].Track.DataType.IsAudio() {
+			extent = &extents[i]
+			break
+		}
+	}
+	if extent == nil {
+		return fmt.Errorf("file %s has no data tracks", f.Name)
+	}
+
+	// ISO image starts with the INDEX 01 sector, pregap is skipped.
+	start := extent.Start
+	if t := extent.Track.StartTime().TotalFrames(); t > start {
+		start = t
+	}
+
+	sr := NewSectorReader(r, *extent)
+	for lba := start; lba < extent.Start+extent.Sectors; lba++ {
+		data, err := sr.ReadUserData(lba)
+		if err != nil {
+			return err
+		}
+		if len(data) != isoSectorSize {
+			return fmt.Errorf("sector %d has %d bytes of user data (MODE2 form 2?), %d expected",
+				lba, len(data), isoSectorSize)
+		}
+		if _, err := w.Write(data); err != nil {
+			return errors.Wrap(err, "failed to write ISO image")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package cue
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestExtractISO(t *testing.T) {
+	var tests = []struct {
+		dataType string
+		sectors  [][]byte
+		iso      []byte
+	}{
+		{"MODE1/2352",
+			[][]byte{rawSector(1, false, 1), rawSector(1, false, 2)},
+			append(bytes.Repeat([]byte{1}, 2048), bytes.Repeat([]byte{2}, 2048)...)},
+		{"MODE2/2352",
+			[][]byte{rawSector(2, false, 3)},
+			bytes.Repeat([]byte{3}, 2048)},
+		{"MODE1/2048",
+			[][]byte{bytes.Repeat([]byte{4}, 2048), bytes.Repeat([]byte{5}, 2048)},
+			append(bytes.Repeat([]byte{4}, 2048), bytes.Repeat([]byte{5}, 2048)...)},
+		{"MODE2/2352",
+			[][]byte{rawSector(2, false, 3), rawSector(2, true, 3)},
+			nil},
+	}
+
+	for _, tt := range tests {
+		sheet, err := Parse(strings.NewReader(fmt.Sprintf(`FILE "image.bin" BINARY
+  TRACK 01 %s
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    INDEX 01 %s
+`, tt.dataType, TimeFromFrames(len(tt.sectors)))))
+		if err != nil {
+			t.Fatalf("Failed to parse sheet. %s", err.Error())
+		}
+
+		var image bytes.Buffer
+		image.Write(bytes.Join(tt.sectors, nil))
+		image.Write(make([]byte, rawSectorSize))
+
+		f, track := sheet.FirstDataTrack()
+		if track != f.Tracks[0] {
+			t.Fatalf("%s: unexpected first data track %d", tt.dataType, track.Number)
+		}
+
+		var iso bytes.Buffer
+		err = ExtractISO(&iso, bytes.NewReader(image.Bytes()), int64(image.Len()), f)
+		if tt.iso == nil {
+			if err == nil {
+				t.Fatalf("%s: form 2 sectors accepted", tt.dataType)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: failed to extract ISO. %s", tt.dataType, err.Error())
+		}
+		if !bytes.Equal(iso.Bytes(), tt.iso) {
+			t.Fatalf("%s: unexpected ISO image data", tt.dataType)
+		}
+	}
+}
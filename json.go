@@ -0,0 +1,97 @@
+package cue
+
+import "encoding/json"
+
+// MarshalJSON implements json.Marshaler.
+// Absent files are encoded as empty array, as the schema requires.
+func (s Sheet) MarshalJSON() ([]byte, error) {
+	type sheet Sheet
+	v := sheet(s)
+	if v.Files == nil {
+		v.Files = []*File{}
+	}
+	return json.Marshal(v)
+}
+
+// MarshalJSON implements json.Marshaler.
+// Absent tracks are encoded as empty array, as the schema requires.
+func (f File) MarshalJSON() ([]byte, error) {
+	type file File
+	v := file(f)
+	if v.Tracks == nil {
+		v.Tracks = []*Track{}
+	}
+	return json.Marshal(v)
+}
+
+// MarshalJSON implements json.Marshaler.
+// Absent indexes are encoded as empty array, as the schema requires.
+func (t Track) MarshalJSON() ([]byte, error) {
+	type track Track
+	v := track(t)
+	if v.Indexes == nil {
+		v.Indexes = []Index{}
+	}
+	return json.Marshal(v)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+// File type is encoded as FILE command keyword.
+func (t FileType) MarshalText() ([]byte, error) {
+	if _, err := ParseFileType(t.String()); err != nil {
+		return nil, err
+	}
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *FileType) UnmarshalText(text []byte) (err error) {
+	*t, err = ParseFileType(string(text))
+	return
+}
+
+// MarshalText implements encoding.TextMarshaler.
+// Track datatype is encoded as TRACK command keyword.
+func (t TrackDataType) MarshalText() ([]byte, error) {
+	if _, err := ParseDataType(t.String()); err != nil {
+		return nil, err
+	}
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *TrackDataType) UnmarshalText(text []byte) (err error) {
+	*t, err = ParseDataType(string(text))
+	return
+}
+
+// MarshalText implements encoding.TextMarshaler.
+// Track flag is encoded as FLAGS command keyword.
+func (f TrackFlag) MarshalText() ([]byte, error) {
+	if _, err := ParseTrackFlag(f.String()); err != nil {
+		return nil, err
+	}
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (f *TrackFlag) UnmarshalText(text []byte) (err error) {
+	*f, err = ParseTrackFlag(string(text))
+	return
+}
+
+// MarshalText implements encoding.TextMarshaler.
+// Time is encoded in the mm:ss:ff form.
+func (time Time) MarshalText() ([]byte, error) {
+	return []byte(time.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (time *Time) UnmarshalText(text []byte) error {
+	min, sec, frames, err := parseTime(string(text))
+	if err != nil {
+		return err
+	}
+	*time = Time{min, sec, frames}
+	return nil
+}
@@ -0,0 +1,134 @@
+package cue
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	const input = `FILE "image.bin" BINARY
+  TRACK 01 MODE1/2352
+    FLAGS DCP PRE
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    PREGAP 00:02:00
+    INDEX 01 10:01:74
+`
+	sheet, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	data, err := json.Marshal(sheet)
+	if err != nil {
+		t.Fatalf("Failed to marshal sheet. %s", err.Error())
+	}
+	for _, s := range []string{`"type":"BINARY"`, `"dataType":"MODE1/2352"`, `"flags":["DCP","PRE"]`,
+		`"pregap":"00:02:00"`, `"time":"10:01:74"`} {
+		if !strings.Contains(string(data), s) {
+			t.Fatalf("%s expected in %s", s, data)
+		}
+	}
+
+	decoded := new(Sheet)
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatalf("Failed to unmarshal sheet. %s", err.Error())
+	}
+	if !reflect.DeepEqual(sheet, decoded) {
+		t.Fatalf("decoded sheet differs from the original one")
+	}
+
+	if err := json.Unmarshal([]byte(`{"files":[{"name":"a","type":"FLAC"}]}`), decoded); err == nil {
+		t.Fatalf("unknown file type accepted")
+	}
+}
+
+// schemaProperties collects all property names described by the schema.
+func schemaProperties(v interface{}, props map[string]bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if p, ok := v["properties"].(map[string]interface{}); ok {
+			for k := range p {
+				props[k] = true
+			}
+		}
+		for _, c := range v {
+			schemaProperties(c, props)
+		}
+	case []interface{}:
+		for _, c := range v {
+			schemaProperties(c, props)
+		}
+	}
+}
+
+// jsonKeys collects all object keys of the JSON value.
+func jsonKeys(v interface{}, keys map[string]bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, c := range v {
+			keys[k] = true
+			jsonKeys(c, keys)
+		}
+	case []interface{}:
+		for _, c := range v {
+			jsonKeys(c, keys)
+		}
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	data, err := ioutil.ReadFile("sheet.schema.json")
+	if err != nil {
+		t.Fatalf("Failed to read schema. %s", err.Error())
+	}
+	var schema interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("Failed to parse schema. %s", err.Error())
+	}
+	props := make(map[string]bool)
+	schemaProperties(schema, props)
+
+	file, err := os.Open("test.cue")
+	if err != nil {
+		t.Fatalf("Failed to open file. %s", err.Error())
+	}
+	defer file.Close()
+	sheet, err := Parse(file, 40*60)
+	if err != nil {
+		t.Fatalf("Failed to parse file. %s", err.Error())
+	}
+	sheet.Files[0].Tracks[1].Indexes[0].InPreviousFile = true
+
+	if data, err = json.Marshal(sheet); err != nil {
+		t.Fatalf("Failed to marshal sheet. %s", err.Error())
+	}
+	var v interface{}
+	json.Unmarshal(data, &v)
+	keys := make(map[string]bool)
+	jsonKeys(v, keys)
+
+	for k := range keys {
+		if !props[k] {
+			t.Fatalf("property %s is not described by the schema", k)
+		}
+	}
+
+	// Absent arrays are encoded as empty arrays, not null.
+	empty := &Sheet{}
+	if data, _ := json.Marshal(empty); string(data) != `{"files":[]}` {
+		t.Fatalf("unexpected empty sheet %s", data)
+	}
+	empty.AddFile("a.wav", FileTypeWave)
+	if data, _ := json.Marshal(empty); !strings.Contains(string(data), `"tracks":[]`) {
+		t.Fatalf("unexpected empty file %s", data)
+	}
+	empty.AddTrack(DataTypeAudio)
+	if data, _ := json.Marshal(empty); !strings.Contains(string(data), `"indexes":[]`) {
+		t.Fatalf("unexpected empty track %s", data)
+	}
+}
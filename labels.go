@@ -0,0 +1,244 @@
+package cue
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// gapLabel is the name of the label (region) of the track gap.
+const gapLabel = "INDEX 00"
+
+// labelRegion is the label (DAW region) of the track or its gap in frames.
+type labelRegion struct {
+	start int
+	// Region end, equal to start if unknown.
+	end  int
+	name string
+}
+
+// labelRegions returns one region per track from INDEX 01 to the next track
+// first index. With gaps, regions of tracks INDEX 00 are added before them.
+func labelRegions(sheet *Sheet, gaps bool) ([]labelRegion, error) {
+	chapters, err := sheet.Chapters()
+	if err != nil {
+		return nil, err
+	}
+
+	var regions []labelRegion
+	spans := timeline(sheet)
+	for i, c := range chapters {
+		r := labelRegion{start: c.Start.TotalFrames(), end: c.End.TotalFrames(), name: c.name(sheet.Performer)}
+		if i == len(chapters)-1 && r.end == 0 {
+			r.end = r.start
+		}
+		if gaps && i+1 < len(spans) && spans[i+1].gap < spans[i+1].start {
+			r.end = spans[i+1].gap
+		}
+		if gaps && i > 0 && spans[i].gap < spans[i].start {
+			regions = append(regions, labelRegion{start: spans[i].gap, end: spans[i].start, name: gapLabel})
+		}
+		regions = append(regions, r)
+	}
+
+	return regions, nil
+}
+
+// parseSeconds parses decimal seconds with nanoseconds precision,
+// comma decimal separator is accepted too.
+func parseSeconds(s string) (time.Duration, error) {
+	s = strings.Replace(strings.TrimSpace(s), ",", ".", 1)
+	if s == "" || s == "." {
+		return 0, fmt.Errorf("illegal time %s", s)
+	}
+	whole, fraction := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, fraction = s[:i], s[i+1:]
+	}
+	sec, err := strconv.ParseUint(whole, 10, 32)
+	if err != nil && whole != "" {
+		return 0, fmt.Errorf("illegal time %s", s)
+	}
+	ns, err := strconv.ParseUint((fraction + "000000000")[:9], 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("illegal time %s", s)
+	}
+	return time.Duration(sec)*time.Second + time.Duration(ns), nil
+}
+
+// labelsChapters converts labels into chapters, gap labels become gaps
+// of the following chapters.
+func labelsChapters(labels []importedChapter) []importedChapter {
+	var chapters []importedChapter
+	var gap time.Duration
+	for _, l := range labels {
+		if l.title == gapLabel {
+			gap = l.start
+			continue
+		}
+		l.gap, gap = gap, 0
+		chapters = append(chapters, l)
+	}
+	return chapters
+}
+
+// ReadAudacityLabels reads Audacity label track export (tab separated
+// start, end and label lines, times in seconds) into the sheet with one
+// track per label. Labels named "INDEX 00" become gaps of the following
+// tracks. Returns rounding errors of the tracks INDEX 01 times.
+func ReadAudacityLabels(r io.Reader, opts ImportOptions) (*Sheet, []time.Duration, error) {
+	var labels []importedChapter
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		// Spectral selection lines start with backslash.
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, `\`) {
+			continue
+		}
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) < 2 {
+			return nil, nil, fmt.Errorf("line %d: start and end times expected", n)
+		}
+
+		l := importedChapter{}
+		var err error
+		if l.start, err = parseSeconds(fields[0]); err != nil {
+			return nil, nil, errors.Wrapf(err, "line %d", n)
+		}
+		if l.end, err = parseSeconds(fields[1]); err != nil {
+			return nil, nil, errors.Wrapf(err, "line %d", n)
+		}
+		if len(fields) == 3 {
+			l.title = strings.TrimSpace(fields[2])
+		}
+		labels = append(labels, l)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// The end of the last region is the end of the file.
+	chapters := labelsChapters(labels)
+	if l := len(chapters); l > 0 && chapters[l-1].end <= chapters[l-1].start {
+		chapters[l-1].end = -1
+	}
+	return sheetFromChapters(chapters, opts)
+}
+
+// WriteAudacityLabels writes the sheet as Audacity label track with one
+// region per track. With gaps, "INDEX 00" regions are written for tracks
+// gaps. The last label is a point label if the last file duration is unknown.
+func WriteAudacityLabels(w io.Writer, sheet *Sheet, gaps bool) error {
+	regions, err := labelRegions(sheet, gaps)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	seconds := func(frames int) string {
+		ns := TimeFromFrames(frames).nanoseconds()
+		return fmt.Sprintf("%d.%06d", ns/1e9, (ns%1e9+500)/1000)
+	}
+	for _, r := range regions {
+		fmt.Fprintf(&buf, "%s\t%s\t%s\n", seconds(r.start), seconds(r.end), oneLine(r.name))
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// ReadMarkersCSV reads Reaper region/marker manager export (or other CSV
+// with header) into the sheet with one track per marker. Name and Start
+// columns are required, End column is optional. Times are either
+// "[h:]m:ss.fff" or decimal seconds. Markers named "INDEX 00" become gaps
+// of the following tracks. Returns rounding errors of the tracks INDEX 01 times.
+func ReadMarkersCSV(r io.Reader, opts ImportOptions) (*Sheet, []time.Duration, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to read CSV")
+	}
+	if len(records) == 0 {
+		return nil, nil, errors.New("CSV header expected")
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	nameColumn, ok1 := columns["name"]
+	startColumn, ok2 := columns["start"]
+	endColumn, hasEnd := columns["end"]
+	if !ok1 || !ok2 {
+		return nil, nil, errors.New("Name and Start columns are required")
+	}
+
+	parseMarkerTime := func(s string) (time.Duration, error) {
+		if strings.Contains(s, ":") {
+			return parseClock(s)
+		}
+		return parseSeconds(s)
+	}
+
+	var labels []importedChapter
+	for n, rec := range records[1:] {
+		if len(rec) <= nameColumn || len(rec) <= startColumn {
+			return nil, nil, fmt.Errorf("row %d: too few fields", n+2)
+		}
+		l := importedChapter{end: -1, title: strings.TrimSpace(rec[nameColumn])}
+		if l.start, err = parseMarkerTime(rec[startColumn]); err != nil {
+			return nil, nil, errors.Wrapf(err, "row %d", n+2)
+		}
+		if hasEnd && len(rec) > endColumn && strings.TrimSpace(rec[endColumn]) != "" {
+			if l.end, err = parseMarkerTime(rec[endColumn]); err != nil {
+				return nil, nil, errors.Wrapf(err, "row %d", n+2)
+			}
+		}
+		labels = append(labels, l)
+	}
+
+	return sheetFromChapters(labelsChapters(labels), opts)
+}
+
+// WriteMarkersCSV writes the sheet as Reaper region/marker manager CSV with
+// one region per track. With gaps, "INDEX 00" regions are written for tracks
+// gaps. The last track is written as marker if the last file duration is unknown.
+func WriteMarkersCSV(w io.Writer, sheet *Sheet, gaps bool) error {
+	regions, err := labelRegions(sheet, gaps)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	cw.Write([]string{"#", "Name", "Start", "End", "Length"})
+	markersCount, regionsCount := 0, 0
+	for _, r := range regions {
+		if r.end == r.start {
+			markersCount++
+			cw.Write([]string{fmt.Sprintf("M%d", markersCount), r.name, TimeFromFrames(r.start).clock(3), "", ""})
+			continue
+		}
+		regionsCount++
+		cw.Write([]string{
+			fmt.Sprintf("R%d", regionsCount), r.name,
+			TimeFromFrames(r.start).clock(3), TimeFromFrames(r.end).clock(3),
+			TimeFromFrames(r.end - r.start).clock(3),
+		})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,107 @@
+package cue
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const labelsSheet = `FILE "session.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "First"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Second"
+    INDEX 00 03:20:00
+    INDEX 01 03:22:40
+`
+
+func TestAudacityLabels(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(labelsSheet), 400)
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	var buf bytes.Buffer
+	if err := WriteAudacityLabels(&buf, sheet, true); err != nil {
+		t.Fatalf("Failed to write labels. %s", err.Error())
+	}
+	expected := "0.000000\t200.000000\tFirst\n" +
+		"200.000000\t202.533333\tINDEX 00\n" +
+		"202.533333\t400.000000\tSecond\n"
+	if buf.String() != expected {
+		t.Fatalf("expected\n%s\nbut\n%s\nreceived", expected, buf.String())
+	}
+
+	imported, _, err := ReadAudacityLabels(&buf, ImportOptions{File: "session.wav", FileType: FileTypeWave})
+	if err != nil {
+		t.Fatalf("Failed to read labels. %s", err.Error())
+	}
+	var a, b bytes.Buffer
+	Write(&a, sheet)
+	Write(&b, imported)
+	if a.String() != b.String() || imported.Files[0].Duration != 400 {
+		t.Fatalf("expected\n%s\nbut\n%s\nreceived", a.String(), b.String())
+	}
+
+	buf.Reset()
+	WriteAudacityLabels(&buf, sheet, false)
+	if !strings.HasPrefix(buf.String(), "0.000000\t202.533333\tFirst\n") {
+		t.Fatalf("unexpected labels\n%s", buf.String())
+	}
+
+	imported, roundingErrors, err := ReadAudacityLabels(strings.NewReader("0,5\t0,5\tOne\n\\\t100\t200\n61.01\t61.01\tTwo\n"),
+		ImportOptions{})
+	if err != nil {
+		t.Fatalf("Failed to read labels. %s", err.Error())
+	}
+	if tracks := imported.Files[0].Tracks; len(tracks) != 2 || tracks[1].StartTime() != (Time{1, 1, 1}) {
+		t.Fatalf("unexpected tracks %+v", tracks)
+	}
+	if len(roundingErrors) != 2 || roundingErrors[1] != 3333333 {
+		t.Fatalf("unexpected rounding errors %v", roundingErrors)
+	}
+}
+
+func TestMarkersCSV(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(labelsSheet))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMarkersCSV(&buf, sheet, true); err != nil {
+		t.Fatalf("Failed to write markers. %s", err.Error())
+	}
+	expected := "#,Name,Start,End,Length\n" +
+		"R1,First,00:00:00.000,00:03:20.000,00:03:20.000\n" +
+		"R2,INDEX 00,00:03:20.000,00:03:22.533,00:00:02.533\n" +
+		"M1,Second,00:03:22.533,,\n"
+	if buf.String() != expected {
+		t.Fatalf("expected\n%s\nbut\n%s\nreceived", expected, buf.String())
+	}
+
+	imported, _, err := ReadMarkersCSV(&buf, ImportOptions{File: "session.wav", FileType: FileTypeWave})
+	if err != nil {
+		t.Fatalf("Failed to read markers. %s", err.Error())
+	}
+	var a, b bytes.Buffer
+	Write(&a, sheet)
+	Write(&b, imported)
+	if a.String() != b.String() {
+		t.Fatalf("expected\n%s\nbut\n%s\nreceived", a.String(), b.String())
+	}
+
+	imported, _, err = ReadMarkersCSV(strings.NewReader("Start,Name\n12.5,\"Intro, part 1\"\n1:00.000,Main\n"), ImportOptions{})
+	if err != nil {
+		t.Fatalf("Failed to read markers. %s", err.Error())
+	}
+	if tracks := imported.Files[0].Tracks; len(tracks) != 2 || tracks[0].Title != "Intro, part 1" ||
+		tracks[1].StartTime() != (Time{1, 0, 0}) {
+		t.Fatalf("unexpected tracks %+v", tracks)
+	}
+
+	if _, _, err := ReadMarkersCSV(strings.NewReader("#,Title\nM1,One\n"), ImportOptions{}); err == nil {
+		t.Fatalf("expected error for missing columns")
+	}
+}
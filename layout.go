@@ -0,0 +1,237 @@
+package cue
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// GapLayout describes where tracks pregaps (INDEX 00) are stored
+// when the disc image is split into one file per track.
+type GapLayout int
+
+const (
+	// Pregap is appended to the end of the previous track file.
+	GapsAppended GapLayout = iota
+	// Pregap is not stored in any file and described by PREGAP command.
+	GapsLeftOut
+	// Pregap is prepended to the beginning of the track file.
+	GapsPrepended
+)
+
+// SplitFiles converts single-file sheet into the multi-file one with
+// one FILE per track. length is the total length of the image file,
+// name returns file name for the given track.
+// The receiver is not modified.
+func (s *Sheet) SplitFiles(length Time, layout GapLayout, name func(t *Track) string) (*Sheet, error) {
+	if len(s.Files) != 1 {
+		return nil, fmt.Errorf("single file sheet expected, but %d files found", len(s.Files))
+	}
+	src := s.Files[0]
+	tracks := src.Tracks
+	tLen := len(tracks)
+	if tLen == 0 {
+		return nil, errors.New("file has no tracks")
+	}
+
+	if layout < GapsAppended || layout > GapsPrepended {
+		return nil, fmt.Errorf("unknown gap layout: %d", layout)
+	}
+
+	// Calculate files boundaries in frames. The region before
+	// the first track INDEX 01 is always treated as its pregap.
+	gaps := make([]int, tLen)
+	starts := make([]int, tLen)
+	for i, t := range tracks {
+		starts[i] = t.StartTime().TotalFrames()
+		gaps[i] = starts[i]
+		if len(t.Indexes) > 1 && t.Indexes[0].Number == 0 {
+			gaps[i] = t.Indexes[0].Time.TotalFrames()
+		}
+	}
+	gaps[0] = 0
+
+	bounds := make([]int, tLen+1)
+	ends := make([]int, tLen)
+	for i := range tracks {
+		bounds[i] = starts[i]
+		if layout == GapsPrepended {
+			bounds[i] = gaps[i]
+		}
+	}
+	if layout != GapsLeftOut {
+		bounds[0] = 0
+	}
+	bounds[tLen] = length.TotalFrames()
+	for i := range tracks {
+		ends[i] = bounds[i+1]
+		if layout == GapsLeftOut && i+1 < tLen {
+			ends[i] = gaps[i+1]
+		}
+	}
+
+	sheet := s.copyDisc()
+	for i, t := range tracks {
+		if ends[i] < bounds[i] {
+			return nil, fmt.Errorf("track %d ends before it starts", t.Number)
+		}
+
+		track := t.copy()
+		track.Indexes = nil
+		for _, idx := range t.Indexes {
+			abs := idx.Time.TotalFrames()
+			switch {
+			case layout == GapsLeftOut && idx.Number == 0:
+				continue
+			case abs < bounds[i]:
+				idx.Time = TimeFromFrames(abs - bounds[i-1])
+				idx.InPreviousFile = true
+			default:
+				idx.Time = TimeFromFrames(abs - bounds[i])
+				idx.InPreviousFile = false
+			}
+			track.Indexes = append(track.Indexes, idx)
+		}
+		if layout == GapsLeftOut {
+			track.Pregap = TimeFromFrames(track.Pregap.TotalFrames() + starts[i] - gaps[i])
+		}
+
+		sheet.Files = append(sheet.Files, &File{
+			Name:     name(t),
+			Type:     src.Type,
+			Tracks:   []*Track{track},
+			Duration: TimeFromFrames(ends[i] - bounds[i]).Seconds(),
+		})
+	}
+	setPositions(sheet)
+
+	return sheet, nil
+}
+
+// JoinFiles converts multi-file sheet of any gap layout into the single-file
+// one with the given file name. lengths are the lengths of the sheet files.
+// The receiver is not modified.
+func (s *Sheet) JoinFiles(name string, lengths []Time) (*Sheet, error) {
+	fLen := len(s.Files)
+	if fLen == 0 {
+		return nil, errors.New("sheet has no files")
+	}
+	if len(lengths) != fLen {
+		return nil, fmt.Errorf("%d file lengths expected, but %d received", fLen, len(lengths))
+	}
+
+	file := &File{Name: name, Type: s.Files[0].Type}
+	offsets := make([]int, fLen+1)
+	for i, f := range s.Files {
+		offsets[i+1] = offsets[i] + lengths[i].TotalFrames()
+
+		for _, t := range f.Tracks {
+			track := t.copy()
+			for j := range track.Indexes {
+				idx := &track.Indexes[j]
+				offset := offsets[i]
+				if idx.InPreviousFile {
+					if i == 0 {
+						return nil, fmt.Errorf("track %d index %d refers to missing previous file", t.Number, idx.Number)
+					}
+					offset = offsets[i-1]
+				}
+				idx.Time = TimeFromFrames(offset + idx.Time.TotalFrames())
+				idx.InPreviousFile = false
+			}
+			file.Tracks = append(file.Tracks, track)
+		}
+	}
+	file.Duration = TimeFromFrames(offsets[fLen]).Seconds()
+
+	sheet := s.copyDisc()
+	sheet.Files = []*File{file}
+	setPositions(sheet)
+
+	return sheet, nil
+}
+
+// copyDisc returns copy of the sheet disc-level information without files.
+func (s *Sheet) copyDisc() *Sheet {
+	sheet := *s
+	sheet.Comments = append([]string(nil), s.Comments...)
+	sheet.Sessions = append([]Session(nil), s.Sessions...)
+	sheet.Files = nil
+	return &sheet
+}
+
+// copy returns deep copy of the track.
+func (t *Track) copy() *Track {
+	track := *t
+	track.Flags = append([]TrackFlag(nil), t.Flags...)
+	track.Comments = append([]string(nil), t.Comments...)
+	track.Indexes = append([]Index(nil), t.Indexes...)
+	return &track
+}
+
+// span is the track position on the sheet audio timeline (all the sheet
+// files joined one after another) in frames.
+type span struct {
+	track *Track
+	file  *File
+	// Position of the first track index (INDEX 00 or INDEX 01).
+	gap int
+	// Position of the INDEX 01.
+	start int
+	// Position of the next track first index or the end of the last file.
+	// -1 if the last file duration is unknown.
+	end int
+	// Positions of all the track indexes.
+	indexes []int
+}
+
+// timeline returns tracks positions on the sheet audio timeline.
+// Files durations are used to calculate files offsets.
+func timeline(sheet *Sheet) []span {
+	var (
+		spans   []span
+		offset  int
+		prev    int
+		fLength int
+	)
+	for _, f := range sheet.Files {
+		fLength = secondsToFrames(f.Duration)
+		for _, t := range f.Tracks {
+			sp := span{track: t, file: f, start: offset + t.StartTime().TotalFrames()}
+			for i, idx := range t.Indexes {
+				pos := offset + idx.Time.TotalFrames()
+				if idx.InPreviousFile {
+					pos = prev + idx.Time.TotalFrames()
+				}
+				if i == 0 {
+					sp.gap = pos
+				}
+				sp.indexes = append(sp.indexes, pos)
+			}
+			if len(t.Indexes) == 0 {
+				sp.gap = sp.start
+			}
+			if l := len(spans); l > 0 {
+				spans[l-1].end = sp.gap
+			}
+			spans = append(spans, sp)
+		}
+		prev = offset
+		offset += fLength
+	}
+
+	if l := len(spans); l > 0 {
+		spans[l-1].end = -1
+		if fLength > 0 {
+			spans[l-1].end = offset
+		}
+	}
+
+	return spans
+}
+
+// secondsToFrames converts seconds into the nearest number of frames.
+func secondsToFrames(sec float64) int {
+	return int(math.Floor(sec*framesPerSecond + 0.5))
+}
@@ -0,0 +1,137 @@
+package cue
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const layoutImage = `FILE "image.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    INDEX 00 03:58:40
+    INDEX 01 04:00:40
+  TRACK 03 AUDIO
+    INDEX 01 08:00:00
+    INDEX 02 08:30:00
+`
+
+const layoutAppended = `FILE "01.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    INDEX 00 03:58:40
+FILE "02.wav" WAVE
+    INDEX 01 00:00:00
+FILE "03.wav" WAVE
+  TRACK 03 AUDIO
+    INDEX 01 00:00:00
+    INDEX 02 00:30:00
+`
+
+func layoutFileName(t *Track) string {
+	return fmt.Sprintf("%02d.wav", t.Number)
+}
+
+func TestParseGapsAppended(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(layoutAppended))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	for i, f := range sheet.Files {
+		if len(f.Tracks) != 1 || f.Tracks[0].Number != i+1 {
+			t.Fatalf("file %s should contain track %d only", f.Name, i+1)
+		}
+	}
+
+	idx := sheet.Files[1].Tracks[0].Indexes
+	if len(idx) != 2 || !idx[0].InPreviousFile || idx[1].InPreviousFile {
+		t.Fatalf("unexpected track 2 indexes: %v", idx)
+	}
+}
+
+func TestSplitJoinFiles(t *testing.T) {
+	length := Time{12, 0, 0}
+	image, err := Parse(strings.NewReader(layoutImage))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	var tests = []struct {
+		layout  GapLayout
+		lengths []Time
+		indexes [][]Index
+		pregap  Time
+	}{
+		{GapsAppended,
+			[]Time{{4, 0, 40}, {3, 59, 35}, {4, 0, 0}},
+			[][]Index{
+				{{1, Time{0, 0, 0}, false}},
+				{{0, Time{3, 58, 40}, true}, {1, Time{0, 0, 0}, false}},
+				{{1, Time{0, 0, 0}, false}, {2, Time{0, 30, 0}, false}},
+			},
+			Time{}},
+		{GapsPrepended,
+			[]Time{{3, 58, 40}, {4, 1, 35}, {4, 0, 0}},
+			[][]Index{
+				{{1, Time{0, 0, 0}, false}},
+				{{0, Time{0, 0, 0}, false}, {1, Time{0, 2, 0}, false}},
+				{{1, Time{0, 0, 0}, false}, {2, Time{0, 30, 0}, false}},
+			},
+			Time{}},
+		{GapsLeftOut,
+			[]Time{{3, 58, 40}, {3, 59, 35}, {4, 0, 0}},
+			[][]Index{
+				{{1, Time{0, 0, 0}, false}},
+				{{1, Time{0, 0, 0}, false}},
+				{{1, Time{0, 0, 0}, false}, {2, Time{0, 30, 0}, false}},
+			},
+			Time{0, 2, 0}},
+	}
+
+	for _, tt := range tests {
+		split, err := image.SplitFiles(length, tt.layout, layoutFileName)
+		if err != nil {
+			t.Fatalf("layout %d: split failed. %s", tt.layout, err.Error())
+		}
+
+		if len(split.Files) != 3 {
+			t.Fatalf("layout %d: expected 3 files but %d received", tt.layout, len(split.Files))
+		}
+		for i, f := range split.Files {
+			if f.Name != layoutFileName(f.Tracks[0]) {
+				t.Fatalf("layout %d: unexpected file name %s", tt.layout, f.Name)
+			}
+			if f.Duration != tt.lengths[i].Seconds() {
+				t.Fatalf("layout %d: file %d: expected %f duration but %f received",
+					tt.layout, i, tt.lengths[i].Seconds(), f.Duration)
+			}
+			idx := f.Tracks[0].Indexes
+			if fmt.Sprint(idx) != fmt.Sprint(tt.indexes[i]) {
+				t.Fatalf("layout %d: file %d: expected %v indexes but %v received",
+					tt.layout, i, tt.indexes[i], idx)
+			}
+		}
+		if p := split.Files[1].Tracks[0].Pregap; p != tt.pregap {
+			t.Fatalf("layout %d: expected %v pregap but %v received", tt.layout, tt.pregap, p)
+		}
+
+		if tt.layout == GapsLeftOut {
+			continue
+		}
+
+		joined, err := split.JoinFiles("image.wav", tt.lengths)
+		if err != nil {
+			t.Fatalf("layout %d: join failed. %s", tt.layout, err.Error())
+		}
+		for i, track := range joined.Files[0].Tracks {
+			expected := image.Files[0].Tracks[i].Indexes
+			if fmt.Sprint(track.Indexes) != fmt.Sprint(expected) {
+				t.Fatalf("layout %d: track %d: expected %v indexes but %v received",
+					tt.layout, track.Number, expected, track.Indexes)
+			}
+		}
+	}
+}
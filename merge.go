@@ -0,0 +1,468 @@
+package cue
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Conflict is the value changed differently by both sides of Merge.
+type Conflict struct {
+	// Track number in ours sheet (theirs if removed by ours), 0 for disc
+	// level conflicts.
+	Track int `json:"track,omitempty"`
+	// Track identity, empty if tracks are matched by numbers.
+	ID string `json:"id,omitempty"`
+	// Cue command keyword of the value: TITLE, ISRC, INDEX, etc. TRACK for
+	// tracks removed by one side and changed by another and for duplicate
+	// track numbers, FILE for files layout. Candidates of the duplicate
+	// track numbers conflict are the tracks with this number in each sheet,
+	// and ID is empty.
+	Field string `json:"field"`
+	// Index number of the INDEX conflicts.
+	Index int `json:"index,omitempty"`
+	// Candidate values in the cue sheet syntax, empty if absent.
+	Base   string `json:"base,omitempty"`
+	Ours   string `json:"ours,omitempty"`
+	Theirs string `json:"theirs,omitempty"`
+}
+
+// String returns conflict description.
+func (c Conflict) String() string {
+	field := c.Field
+	if field == "INDEX" {
+		field = fmt.Sprintf("INDEX %02d", c.Index)
+	}
+	absent := func(s string) string {
+		if s == "" {
+			return "none"
+		}
+		return s
+	}
+	s := fmt.Sprintf("%s conflict: ours %s, theirs %s (base %s)", field, absent(c.Ours), absent(c.Theirs), absent(c.Base))
+	if c.Track != 0 {
+		s = fmt.Sprintf("track %d: %s", c.Track, s)
+	}
+	return s
+}
+
+// mergeSide returns true if the merge takes theirs value, and conflict if
+// both sides changed the value differently. Ours value is taken on conflict.
+func mergeSide(base, ours, theirs string) (takeTheirs, conflict bool) {
+	switch {
+	case ours == theirs || theirs == base:
+		return false, false
+	case ours == base:
+		return true, false
+	}
+	return false, true
+}
+
+// sheetField is the disc value accessor.
+type sheetField struct {
+	name  string
+	value func(s *Sheet) string
+	copy  func(dst, src *Sheet)
+}
+
+var mergeSheetFields = []sheetField{
+	{"CATALOG", func(s *Sheet) string { return s.Catalog }, func(d, s *Sheet) { d.Catalog = s.Catalog }},
+	{"CDTEXTFILE", func(s *Sheet) string { return diffString(s.CdTextFile) }, func(d, s *Sheet) { d.CdTextFile = s.CdTextFile }},
+	{"PERFORMER", func(s *Sheet) string { return diffString(s.Performer) }, func(d, s *Sheet) { d.Performer = s.Performer }},
+	{"TITLE", func(s *Sheet) string { return diffString(s.Title) }, func(d, s *Sheet) { d.Title = s.Title }},
+	{"SONGWRITER", func(s *Sheet) string { return diffString(s.Songwriter) }, func(d, s *Sheet) { d.Songwriter = s.Songwriter }},
+	{"REM SESSION", func(s *Sheet) string {
+		var values []string
+		for _, f := range discFields(s) {
+			if strings.HasPrefix(f.name, "REM SESSION") {
+				values = append(values, f.name+" "+f.value)
+			}
+		}
+		return strings.Join(values, ", ")
+	}, func(d, s *Sheet) { d.Sessions = append([]Session(nil), s.Sessions...) }},
+}
+
+// trackField is the track value accessor.
+type trackField struct {
+	name  string
+	value func(t *Track) string
+	copy  func(dst, src *Track)
+}
+
+var mergeTrackFields = []trackField{
+	{"TRACK", func(t *Track) string { return fmt.Sprintf("%02d %s", t.Number, t.DataType) }, func(d, s *Track) {
+		d.Number, d.DataType = s.Number, s.DataType
+	}},
+	{"TITLE", func(t *Track) string { return diffString(t.Title) }, func(d, s *Track) { d.Title = s.Title }},
+	{"PERFORMER", func(t *Track) string { return diffString(t.Performer) }, func(d, s *Track) { d.Performer = s.Performer }},
+	{"SONGWRITER", func(t *Track) string { return diffString(t.Songwriter) }, func(d, s *Track) { d.Songwriter = s.Songwriter }},
+	{"FLAGS", func(t *Track) string { return fieldValue(trackFields(t), "FLAGS") }, func(d, s *Track) {
+		d.Flags = append([]TrackFlag(nil), s.Flags...)
+	}},
+	{"ISRC", func(t *Track) string { return t.Isrc }, func(d, s *Track) { d.Isrc = s.Isrc }},
+	{"PREGAP", func(t *Track) string { return timeValue(t.Pregap) }, func(d, s *Track) { d.Pregap = s.Pregap }},
+	{"POSTGAP", func(t *Track) string { return timeValue(t.Postgap) }, func(d, s *Track) { d.Postgap = s.Postgap }},
+}
+
+// fieldValue returns value of the named field, empty if absent.
+func fieldValue(fields []diffField, name string) string {
+	for _, f := range fields {
+		if f.name == name {
+			return f.value
+		}
+	}
+	return ""
+}
+
+// numberValue returns the sheet track with the given number in the cue
+// sheet syntax, empty if absent.
+func numberValue(s *Sheet, number int) string {
+	f, i, err := s.findTrack(number)
+	if err != nil {
+		return ""
+	}
+	return trackSyntax(f.Tracks[i])
+}
+
+// timeValue returns time value, empty for zero time.
+func timeValue(t Time) string {
+	if t.TotalFrames() == 0 {
+		return ""
+	}
+	return t.String()
+}
+
+// indexValue returns index value, empty if the track has no such index.
+func indexValue(t *Track, number int) (Index, string) {
+	for _, idx := range t.Indexes {
+		if idx.Number == number {
+			if idx.InPreviousFile {
+				return idx, idx.Time.String() + " in previous file"
+			}
+			return idx, idx.Time.String()
+		}
+	}
+	return Index{}, ""
+}
+
+// trackSyntax returns the track commands in the cue sheet syntax.
+func trackSyntax(t *Track) string {
+	var buf bytes.Buffer
+	writeTrackHeader(&buf, quote, t)
+	writeIndexes(&buf, t, true)
+	writeIndexes(&buf, t, false)
+	if t.Postgap.TotalFrames() != 0 {
+		fmt.Fprintf(&buf, "    POSTGAP %s\n", t.Postgap)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// trackValue returns all the track values, equal for equal tracks.
+func trackValue(t *Track) string {
+	var values []string
+	for _, f := range mergeTrackFields {
+		values = append(values, f.value(t))
+	}
+	values = append(values, t.Comments...)
+	for _, idx := range t.Indexes {
+		_, v := indexValue(t, idx.Number)
+		values = append(values, fmt.Sprintf("%02d %s", idx.Number, v))
+	}
+	return strings.Join(values, "\n")
+}
+
+// trackKey returns track identity: ID if set, number otherwise.
+func trackKey(t *Track) string {
+	if t.ID != "" {
+		return t.ID
+	}
+	return fmt.Sprintf("#%d", t.Number)
+}
+
+// sheetTracks returns sheet tracks by their keys.
+func sheetTracks(s *Sheet) map[string]*Track {
+	tracks := map[string]*Track{}
+	for _, f := range s.Files {
+		for _, t := range f.Tracks {
+			tracks[trackKey(t)] = t
+		}
+	}
+	return tracks
+}
+
+// layoutValue returns files names and types with the tracks common for all
+// the merged sheets, so added and removed tracks don't change the layout.
+func layoutValue(s *Sheet, common map[string]bool) string {
+	var files []string
+	for _, f := range s.Files {
+		var keys []string
+		for _, t := range f.Tracks {
+			if common[trackKey(t)] {
+				keys = append(keys, fmt.Sprintf("%02d", t.Number))
+			}
+		}
+		files = append(files, fmt.Sprintf("%s %s [%s]", quote(f.Name), f.Type, strings.Join(keys, " ")))
+	}
+	return strings.Join(files, ", ")
+}
+
+// mergeTrack merges track fields and indexes changed by ours and theirs
+// into the copy of ours track. base is nil for tracks added by both sides.
+func mergeTrack(base, ours, theirs *Track) (*Track, []Conflict) {
+	if base == nil {
+		base = &Track{}
+	}
+	merged := ours.copy()
+	var conflicts []Conflict
+	conflict := func(field string, index int, b, o, t string) {
+		conflicts = append(conflicts, Conflict{
+			Track: ours.Number, ID: ours.ID, Field: field, Index: index, Base: b, Ours: o, Theirs: t,
+		})
+	}
+
+	for _, f := range mergeTrackFields {
+		b, o, t := f.value(base), f.value(ours), f.value(theirs)
+		takeTheirs, isConflict := mergeSide(b, o, t)
+		if isConflict {
+			conflict(f.name, 0, b, o, t)
+		} else if takeTheirs {
+			f.copy(merged, theirs)
+		}
+	}
+
+	numbers := map[int]bool{}
+	for _, t := range []*Track{base, ours, theirs} {
+		for _, idx := range t.Indexes {
+			numbers[idx.Number] = true
+		}
+	}
+	var sorted []int
+	for n := range numbers {
+		sorted = append(sorted, n)
+	}
+	sort.Ints(sorted)
+
+	merged.Comments = mergeComments(base.Comments, ours.Comments, theirs.Comments)
+
+	var indexes []Index
+	indexConflicts := len(conflicts)
+	for _, n := range sorted {
+		_, b := indexValue(base, n)
+		oIdx, o := indexValue(ours, n)
+		tIdx, t := indexValue(theirs, n)
+		takeTheirs, isConflict := mergeSide(b, o, t)
+		if isConflict {
+			conflict("INDEX", n, b, o, t)
+		}
+		switch {
+		case takeTheirs && t != "":
+			indexes = append(indexes, tIdx)
+		case !takeTheirs && o != "":
+			indexes = append(indexes, oIdx)
+		}
+	}
+
+	// Indexes moved by different sides may be out of order.
+	for i := 1; i < len(indexes) && len(conflicts) == indexConflicts; i++ {
+		prev, idx := indexes[i-1], indexes[i]
+		if prev.InPreviousFile == idx.InPreviousFile && prev.Time.TotalFrames() >= idx.Time.TotalFrames() ||
+			!prev.InPreviousFile && idx.InPreviousFile {
+			_, b := indexValue(base, idx.Number)
+			_, o := indexValue(ours, idx.Number)
+			_, t := indexValue(theirs, idx.Number)
+			conflict("INDEX", idx.Number, b, o, t)
+		}
+	}
+	if len(conflicts) == indexConflicts {
+		merged.Indexes = indexes
+	}
+
+	return merged, conflicts
+}
+
+// Merge applies changes made by ours and theirs sheets to their common base
+// sheet and returns the result with the conflicts. Disc values, comments,
+// tracks values and indexes changed by one side only are taken from that
+// side, values changed by both sides differently are conflicts, and ours
+// values are kept for them. Tracks are matched by their IDs, or by numbers
+// for tracks without ID. Files layout is taken from the side which changed
+// it. Added tracks are placed into the file of the preceding track.
+// The arguments are not modified.
+func Merge(base, ours, theirs *Sheet) (*Sheet, []Conflict) {
+	var conflicts []Conflict
+	merged := ours.copyDisc()
+
+	for _, f := range mergeSheetFields {
+		b, o, t := f.value(base), f.value(ours), f.value(theirs)
+		takeTheirs, conflict := mergeSide(b, o, t)
+		if conflict {
+			conflicts = append(conflicts, Conflict{Field: f.name, Base: b, Ours: o, Theirs: t})
+		} else if takeTheirs {
+			f.copy(merged, theirs)
+		}
+	}
+	merged.Comments = mergeComments(base.Comments, ours.Comments, theirs.Comments)
+
+	baseTracks, ourTracks, theirTracks := sheetTracks(base), sheetTracks(ours), sheetTracks(theirs)
+	common := map[string]bool{}
+	for key := range baseTracks {
+		if ourTracks[key] != nil && theirTracks[key] != nil {
+			common[key] = true
+		}
+	}
+
+	// Files layout.
+	layout, other := ours, theirs
+	b, o, t := layoutValue(base, common), layoutValue(ours, common), layoutValue(theirs, common)
+	takeTheirs, conflict := mergeSide(b, o, t)
+	if conflict {
+		conflicts = append(conflicts, Conflict{Field: "FILE", Base: b, Ours: o, Theirs: t})
+	} else if takeTheirs {
+		layout, other = theirs, ours
+	}
+	layoutTracks := sheetTracks(layout)
+
+	// merged returns the merged track or nil if the track is removed.
+	mergedTrack := func(key string) *Track {
+		bt, ot, tt := baseTracks[key], ourTracks[key], theirTracks[key]
+		switch {
+		case ot != nil && tt != nil:
+			track, trackConflicts := mergeTrack(bt, ot, tt)
+			conflicts = append(conflicts, trackConflicts...)
+			return track
+		case bt == nil && ot != nil:
+			return ot.copy()
+		case bt == nil:
+			return tt.copy()
+		case ot == nil && tt == nil:
+			return nil
+		case ot == nil:
+			if trackValue(tt) != trackValue(bt) {
+				conflicts = append(conflicts, Conflict{
+					Track: tt.Number, ID: tt.ID, Field: "TRACK", Base: trackSyntax(bt), Theirs: trackSyntax(tt),
+				})
+			}
+			return nil
+		default:
+			if trackValue(ot) != trackValue(bt) {
+				conflicts = append(conflicts, Conflict{
+					Track: ot.Number, ID: ot.ID, Field: "TRACK", Base: trackSyntax(bt), Ours: trackSyntax(ot),
+				})
+				return ot.copy()
+			}
+			return nil
+		}
+	}
+
+	for _, f := range layout.Files {
+		file := *f
+		file.Tracks = nil
+		for _, t := range f.Tracks {
+			if track := mergedTrack(trackKey(t)); track != nil {
+				file.Tracks = append(file.Tracks, track)
+			}
+		}
+		merged.Files = append(merged.Files, &file)
+	}
+
+	// Tracks missing in the layout side.
+	for _, f := range other.Files {
+		for _, t := range f.Tracks {
+			key := trackKey(t)
+			if layoutTracks[key] != nil {
+				continue
+			}
+			if track := mergedTrack(key); track != nil {
+				insertTrack(merged, track)
+			}
+		}
+	}
+	// Both sides may add or renumber tracks into the same numbers.
+	prev := 0
+	for _, f := range merged.Files {
+		for _, t := range f.Tracks {
+			if t.Number <= prev {
+				conflicts = append(conflicts, Conflict{
+					Track: t.Number, Field: "TRACK", Base: numberValue(base, t.Number),
+					Ours: numberValue(ours, t.Number), Theirs: numberValue(theirs, t.Number),
+				})
+			}
+			prev = t.Number
+		}
+	}
+	setPositions(merged)
+
+	return merged, conflicts
+}
+
+// insertTrack inserts the track into the file of the track with the
+// preceding number in the numbers order, or into the first file.
+func insertTrack(sheet *Sheet, track *Track) {
+	if len(sheet.Files) == 0 {
+		sheet.Files = append(sheet.Files, &File{})
+	}
+	target := sheet.Files[0]
+	for _, f := range sheet.Files {
+		for _, t := range f.Tracks {
+			if t.Number < track.Number {
+				target = f
+			}
+		}
+	}
+	target.Tracks = append(target.Tracks, track)
+	sort.SliceStable(target.Tracks, func(i, j int) bool {
+		return target.Tracks[i].Number < target.Tracks[j].Number
+	})
+}
+
+// mergeComments returns ours comments without the comments removed by
+// theirs, followed by the comments added by theirs only. Comments are
+// compared as multisets of lines.
+func mergeComments(base, ours, theirs []string) []string {
+	count := func(comments []string) map[string]int {
+		m := map[string]int{}
+		for _, c := range comments {
+			m[c]++
+		}
+		return m
+	}
+	baseCount, ourCount, theirCount := count(base), count(ours), count(theirs)
+
+	// Number of the comment lines in the result.
+	result := map[string]int{}
+	for _, m := range []map[string]int{baseCount, ourCount, theirCount} {
+		for c := range m {
+			ourDelta, theirDelta := ourCount[c]-baseCount[c], theirCount[c]-baseCount[c]
+			n := baseCount[c] + ourDelta + theirDelta
+			if ourDelta == theirDelta {
+				n = ourCount[c]
+			}
+			if n < 0 {
+				n = 0
+			}
+			result[c] = n
+		}
+	}
+
+	var merged []string
+	for _, c := range ours {
+		if result[c] > 0 && ourCount[c] > 0 {
+			// Extra lines are removed from the beginning.
+			if ourCount[c] > result[c] {
+				ourCount[c]--
+				continue
+			}
+			ourCount[c]--
+			result[c]--
+			merged = append(merged, c)
+		}
+	}
+	for _, c := range theirs {
+		if result[c] > 0 {
+			result[c]--
+			merged = append(merged, c)
+		}
+	}
+	return merged
+}
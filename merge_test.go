@@ -0,0 +1,158 @@
+package cue
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const mergeBase = `REM GENRE Rock
+REM DATE 1990
+PERFORMER "Band"
+TITLE "Album"
+FILE "album.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "One"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Two"
+    INDEX 00 03:00:00
+    INDEX 01 03:02:00
+  TRACK 03 AUDIO
+    TITLE "Three"
+    INDEX 01 06:00:00
+`
+
+// mergeSheet parses the base sheet with the replacements.
+func mergeSheet(t *testing.T, oldnew ...string) *Sheet {
+	sheet, err := Parse(strings.NewReader(strings.NewReplacer(oldnew...).Replace(mergeBase)))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+	return sheet
+}
+
+func TestMerge(t *testing.T) {
+	base := mergeSheet(t)
+	// Metadata fixes.
+	ours := mergeSheet(t,
+		`TITLE "Two"`, `TITLE "Two (Live)"`,
+		`TITLE "Three"`, `TITLE "Three"
+    ISRC USABC9900003`,
+		"REM DATE 1990\n", "REM DATE 1991\n",
+	)
+	// Timing fixes.
+	theirs := mergeSheet(t,
+		"INDEX 01 03:02:00", "INDEX 01 03:02:12",
+		"INDEX 01 06:00:00", "INDEX 01 06:00:00\n    INDEX 02 07:00:00",
+		"REM GENRE Rock\n", "",
+	)
+
+	merged, conflicts := Merge(base, ours, theirs)
+	if len(conflicts) != 0 {
+		t.Fatalf("unexpected conflicts %v", conflicts)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, merged); err != nil {
+		t.Fatalf("Failed to write sheet. %s", err.Error())
+	}
+	expected := strings.NewReplacer(
+		"REM GENRE Rock\nREM DATE 1990\n", "REM DATE 1991\n",
+		`TITLE "Two"`, `TITLE "Two (Live)"`,
+		"INDEX 01 03:02:00", "INDEX 01 03:02:12",
+		"INDEX 01 06:00:00", "ISRC USABC9900003\n    INDEX 01 06:00:00\n    INDEX 02 07:00:00",
+	).Replace(mergeBase)
+	if buf.String() != expected {
+		t.Fatalf("unexpected merged sheet\n%s", buf.String())
+	}
+	if reflect.DeepEqual(base, mergeSheet(t)) == false || merged.Files[0].Tracks[1] == ours.Files[0].Tracks[1] {
+		t.Fatalf("merged sheets are modified or shared")
+	}
+}
+
+func TestMergeConflicts(t *testing.T) {
+	base := mergeSheet(t)
+	ours := mergeSheet(t,
+		`TITLE "Album"`, `TITLE "Album (Remaster)"`,
+		"INDEX 00 03:00:00", "INDEX 00 03:01:00",
+		`TITLE "Three"`, `TITLE "3"`,
+	)
+	theirs := mergeSheet(t,
+		`TITLE "Album"`, `TITLE "Album (Deluxe)"`,
+		"INDEX 00 03:00:00", "INDEX 00 02:59:00",
+		// Track 3 is removed.
+		"  TRACK 03 AUDIO\n    TITLE \"Three\"\n    INDEX 01 06:00:00\n", "",
+	)
+
+	merged, conflicts := Merge(base, ours, theirs)
+	var lines []string
+	for _, c := range conflicts {
+		lines = append(lines, c.String())
+	}
+	expected := []string{
+		`TITLE conflict: ours "Album (Remaster)", theirs "Album (Deluxe)" (base "Album")`,
+		`track 2: INDEX 00 conflict: ours 03:01:00, theirs 02:59:00 (base 03:00:00)`,
+		"track 3: TRACK conflict: ours   TRACK 03 AUDIO\n    TITLE \"3\"\n    INDEX 01 06:00:00, theirs none (base   TRACK 03 AUDIO\n    TITLE \"Three\"\n    INDEX 01 06:00:00)",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("unexpected conflicts\n%s", strings.Join(lines, "\n"))
+	}
+	// Ours values are kept.
+	if merged.Title != "Album (Remaster)" || merged.TracksCount() != 3 ||
+		merged.Files[0].Tracks[1].Indexes[0].Time != (Time{3, 1, 0}) {
+		t.Fatalf("unexpected merged sheet %+v", merged)
+	}
+}
+
+func TestMergeTrackIDs(t *testing.T) {
+	setIDs := func(s *Sheet, ids ...string) *Sheet {
+		for i, t := range s.Files[0].Tracks {
+			t.ID = ids[i]
+		}
+		return s
+	}
+	base := setIDs(mergeSheet(t), "a", "b", "c")
+	// Hidden track is inserted before track 2, so tracks are renumbered.
+	ours := setIDs(mergeSheet(t,
+		"  TRACK 03 AUDIO\n    TITLE \"Three\"", "  TRACK 04 AUDIO\n    TITLE \"Three\"",
+		"  TRACK 02 AUDIO\n    TITLE \"Two\"", "  TRACK 02 AUDIO\n    TITLE \"Hidden\"\n    INDEX 01 02:00:00\n  TRACK 03 AUDIO\n    TITLE \"Two\"",
+	), "a", "h", "b", "c")
+	theirs := setIDs(mergeSheet(t, `TITLE "Three"`, `TITLE "Tres"`), "a", "b", "c")
+
+	merged, conflicts := Merge(base, ours, theirs)
+	if len(conflicts) != 0 {
+		t.Fatalf("unexpected conflicts %v", conflicts)
+	}
+	tracks := merged.Files[0].Tracks
+	if len(tracks) != 4 || tracks[3].Number != 4 || tracks[3].Title != "Tres" || tracks[3].ID != "c" {
+		t.Fatalf("unexpected tracks %+v", tracks)
+	}
+
+	// Tracks added by both sides with the same number.
+	ours = setIDs(mergeSheet(t), "a", "b", "c")
+	ours.Files[0].Tracks = append(ours.Files[0].Tracks, &Track{ID: "x", Number: 4, Indexes: []Index{{1, Time{8, 0, 0}, false}}})
+	theirs = setIDs(mergeSheet(t), "a", "b", "c")
+	theirs.Files[0].Tracks = append(theirs.Files[0].Tracks, &Track{ID: "y", Number: 4, Indexes: []Index{{1, Time{9, 0, 0}, false}}})
+	_, conflicts = Merge(base, ours, theirs)
+	if len(conflicts) != 1 || conflicts[0].Field != "TRACK" || conflicts[0].Base != "" ||
+		conflicts[0].Ours != "  TRACK 04 AUDIO\n    INDEX 01 08:00:00" || conflicts[0].Theirs != "  TRACK 04 AUDIO\n    INDEX 01 09:00:00" {
+		t.Fatalf("unexpected conflicts %v", conflicts)
+	}
+}
+
+func TestMergeComments(t *testing.T) {
+	var tests = []struct {
+		base, ours, theirs, expected []string
+	}{
+		{[]string{"A", "B"}, []string{"A", "B", "C"}, []string{"B", "D"}, []string{"B", "C", "D"}},
+		{nil, []string{"A"}, []string{"A"}, []string{"A"}},
+		{[]string{"A", "A"}, []string{"A"}, []string{"A", "A"}, []string{"A"}},
+		{[]string{"A"}, []string{}, []string{}, nil},
+	}
+	for _, test := range tests {
+		if got := mergeComments(test.base, test.ours, test.theirs); !reflect.DeepEqual(got, test.expected) {
+			t.Fatalf("expected %v for %v %v %v, got %v", test.expected, test.base, test.ours, test.theirs, got)
+		}
+	}
+}
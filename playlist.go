@@ -0,0 +1,222 @@
+package cue
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// playlistPath returns path of the sheet file relative to the playlist
+// directory. Files names are relative to the sheet directory, absolute
+// path is returned if relative one can't be built.
+func playlistPath(name, sheetDir, playlistDir string) string {
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(sheetDir, name)
+	}
+	if rel, err := filepath.Rel(playlistDir, path); err == nil {
+		return rel
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// playlistURI returns playlist path as URI reference.
+func playlistURI(path string) string {
+	segments := strings.Split(filepath.ToSlash(path), "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	uri := strings.Join(segments, "/")
+	if filepath.IsAbs(path) {
+		if !strings.HasPrefix(uri, "/") {
+			// Windows drive path.
+			uri = "/" + uri
+		}
+		return "file://" + uri
+	}
+	return uri
+}
+
+// playlistEntry is the track description shared by the playlist formats.
+type playlistEntry struct {
+	track     *Track
+	path      string
+	performer string
+	// Track end position in seconds, zero if unknown.
+	end float64
+}
+
+// playlistEntries returns sheet tracks with the files paths relative to
+// the playlist directory.
+func playlistEntries(sheet *Sheet, sheetDir, playlistDir string) (entries []playlistEntry) {
+	for _, f := range sheet.Files {
+		path := playlistPath(f.Name, sheetDir, playlistDir)
+		for _, t := range f.Tracks {
+			e := playlistEntry{track: t, path: path, performer: t.Performer}
+			if e.performer == "" {
+				e.performer = sheet.Performer
+			}
+			if t.EndPosition > t.StartPosition {
+				e.end = t.EndPosition
+			}
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// name returns "Performer - Title" entry name.
+func (e playlistEntry) name() string {
+	title := e.track.Title
+	if title == "" {
+		title = fmt.Sprintf("Track %02d", e.track.Number)
+	}
+	if e.performer != "" {
+		title = e.performer + " - " + title
+	}
+	return oneLine(title)
+}
+
+// WriteM3U8 writes the sheet as extended M3U8 playlist with one entry per
+// track. Tracks positions in their files are set by VLC start-time and
+// stop-time options. Files names are relative to the sheetDir and written
+// relative to the playlistDir.
+func WriteM3U8(w io.Writer, sheet *Sheet, sheetDir, playlistDir string) error {
+	var buf bytes.Buffer
+	buf.WriteString("#EXTM3U\n")
+	if sheet.Title != "" {
+		fmt.Fprintf(&buf, "#PLAYLIST:%s\n", oneLine(sheet.Title))
+	}
+
+	for _, e := range playlistEntries(sheet, sheetDir, playlistDir) {
+		duration := -1
+		if e.end > 0 {
+			duration = int(math.Floor(e.track.Duration() + 0.5))
+		}
+		fmt.Fprintf(&buf, "#EXTINF:%d,%s\n", duration, e.name())
+		fmt.Fprintf(&buf, "#EXTVLCOPT:start-time=%.3f\n", e.track.StartPosition)
+		if e.end > 0 {
+			fmt.Fprintf(&buf, "#EXTVLCOPT:stop-time=%.3f\n", e.end)
+		}
+		fmt.Fprintf(&buf, "%s\n", e.path)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// XSPF playlist elements.
+type (
+	xspfPlaylist struct {
+		XMLName xml.Name    `xml:"playlist"`
+		Version int         `xml:"version,attr"`
+		XMLNS   string      `xml:"xmlns,attr"`
+		VLC     string      `xml:"xmlns:vlc,attr"`
+		Title   string      `xml:"title,omitempty"`
+		Creator string      `xml:"creator,omitempty"`
+		Tracks  []xspfTrack `xml:"trackList>track"`
+	}
+	xspfTrack struct {
+		Location  string        `xml:"location"`
+		Title     string        `xml:"title,omitempty"`
+		Creator   string        `xml:"creator,omitempty"`
+		Album     string        `xml:"album,omitempty"`
+		TrackNum  int           `xml:"trackNum"`
+		Duration  int64         `xml:"duration,omitempty"`
+		Extension xspfExtension `xml:"extension"`
+	}
+	xspfExtension struct {
+		Application string   `xml:"application,attr"`
+		ID          int      `xml:"vlc:id"`
+		Options     []string `xml:"vlc:option"`
+	}
+)
+
+// WriteXSPF writes the sheet as XSPF playlist with one track per sheet
+// track. Tracks positions in their files are set by VLC extension
+// start-time and stop-time options. Files names are relative to the
+// sheetDir and written relative to the playlistDir.
+func WriteXSPF(w io.Writer, sheet *Sheet, sheetDir, playlistDir string) error {
+	doc := xspfPlaylist{
+		Version: 1,
+		XMLNS:   "http://xspf.org/ns/0/",
+		VLC:     "http://www.videolan.org/vlc/playlist/ns/0/",
+		Title:   sheet.Title,
+		Creator: sheet.Performer,
+	}
+	for i, e := range playlistEntries(sheet, sheetDir, playlistDir) {
+		track := xspfTrack{
+			Location: playlistURI(e.path),
+			Title:    e.track.Title,
+			Creator:  e.performer,
+			Album:    sheet.Title,
+			TrackNum: e.track.Number,
+			Extension: xspfExtension{
+				Application: "http://www.videolan.org/vlc/playlist/0",
+				ID:          i,
+				Options:     []string{fmt.Sprintf("start-time=%.3f", e.track.StartPosition)},
+			},
+		}
+		if e.end > 0 {
+			track.Duration = int64(math.Floor(e.track.Duration()*1000 + 0.5))
+			track.Extension.Options = append(track.Extension.Options, fmt.Sprintf("stop-time=%.3f", e.end))
+		}
+		doc.Tracks = append(doc.Tracks, track)
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.Write(data)
+	buf.WriteByte('\n')
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// WritePLS writes the sheet as PLS playlist. PLS can't describe positions
+// in the files, so one entry per FILE is written: named after its track
+// for single-track files and after the disc for others.
+func WritePLS(w io.Writer, sheet *Sheet, sheetDir, playlistDir string) error {
+	var buf bytes.Buffer
+	buf.WriteString("[playlist]\n")
+
+	disc := oneLine(sheet.Title)
+	if sheet.Performer != "" && disc != "" {
+		disc = oneLine(sheet.Performer) + " - " + disc
+	}
+	for i, f := range sheet.Files {
+		title := disc
+		if len(f.Tracks) == 1 {
+			e := playlistEntry{track: f.Tracks[0], performer: f.Tracks[0].Performer}
+			if e.performer == "" {
+				e.performer = sheet.Performer
+			}
+			title = e.name()
+		}
+		length := -1
+		if f.Duration > 0 {
+			length = int(math.Floor(f.Duration + 0.5))
+		}
+		fmt.Fprintf(&buf, "File%d=%s\n", i+1, playlistPath(f.Name, sheetDir, playlistDir))
+		if title != "" {
+			fmt.Fprintf(&buf, "Title%d=%s\n", i+1, title)
+		}
+		fmt.Fprintf(&buf, "Length%d=%d\n", i+1, length)
+	}
+	fmt.Fprintf(&buf, "NumberOfEntries=%d\nVersion=2\n", len(sheet.Files))
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,104 @@
+package cue
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const playlistSheet = `PERFORMER "Band"
+TITLE "Live & Loud"
+FILE "audio/live set.flac" WAVE
+  TRACK 01 AUDIO
+    TITLE "Opening"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Encore"
+    PERFORMER "Guest"
+    PREGAP 00:02:00
+    INDEX 01 03:22:40
+`
+
+func TestWriteM3U8(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(playlistSheet), 400)
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	var buf bytes.Buffer
+	if err := WriteM3U8(&buf, sheet, "/music/band", "/music/lists"); err != nil {
+		t.Fatalf("Failed to write playlist. %s", err.Error())
+	}
+	expected := `#EXTM3U
+#PLAYLIST:Live & Loud
+#EXTINF:203,Band - Opening
+#EXTVLCOPT:start-time=0.000
+#EXTVLCOPT:stop-time=202.533
+../band/audio/live set.flac
+#EXTINF:197,Guest - Encore
+#EXTVLCOPT:start-time=202.533
+#EXTVLCOPT:stop-time=400.000
+../band/audio/live set.flac
+`
+	if buf.String() != expected {
+		t.Fatalf("expected\n%s\nbut\n%s\nreceived", expected, buf.String())
+	}
+
+	sheet, _ = Parse(strings.NewReader(playlistSheet))
+	buf.Reset()
+	WriteM3U8(&buf, sheet, "band", "band")
+	if s := "#EXTINF:-1,Guest - Encore\n#EXTVLCOPT:start-time=202.533\naudio/live set.flac\n"; !strings.HasSuffix(buf.String(), s) {
+		t.Fatalf("expected %q suffix in\n%s", s, buf.String())
+	}
+}
+
+func TestWriteXSPF(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(playlistSheet), 400)
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	var buf bytes.Buffer
+	if err := WriteXSPF(&buf, sheet, "/music/band", "/music"); err != nil {
+		t.Fatalf("Failed to write playlist. %s", err.Error())
+	}
+	for _, s := range []string{
+		`<playlist version="1" xmlns="http://xspf.org/ns/0/" xmlns:vlc="http://www.videolan.org/vlc/playlist/ns/0/">`,
+		"<title>Live &amp; Loud</title>",
+		"<location>band/audio/live%20set.flac</location>",
+		"<creator>Guest</creator>",
+		"<duration>197467</duration>",
+		"<vlc:option>start-time=202.533</vlc:option>",
+		"<vlc:option>stop-time=400.000</vlc:option>",
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Fatalf("expected %q in\n%s", s, buf.String())
+		}
+	}
+
+	if uri := playlistURI("/music/a b.flac"); uri != "file:///music/a%20b.flac" {
+		t.Fatalf("unexpected URI %s", uri)
+	}
+}
+
+func TestWritePLS(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(playlistSheet), 400)
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	var buf bytes.Buffer
+	if err := WritePLS(&buf, sheet, "", ""); err != nil {
+		t.Fatalf("Failed to write playlist. %s", err.Error())
+	}
+	expected := `[playlist]
+File1=audio/live set.flac
+Title1=Band - Live & Loud
+Length1=400
+NumberOfEntries=1
+Version=2
+`
+	if buf.String() != expected {
+		t.Fatalf("expected\n%s\nbut\n%s\nreceived", expected, buf.String())
+	}
+}
@@ -0,0 +1,164 @@
+package cue
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// Size of the raw CD sector.
+	rawSectorSize = 2352
+	// Size of the sync pattern and header of the raw data sector.
+	sectorHeaderSize = 16
+	// Size of the MODE2 sector subheader.
+	subheaderSize = 8
+	// MODE2 subheader submode bit which marks form 2 sectors.
+	submodeForm2 = 0x20
+)
+
+// Extent describes location of the track data in the binary file.
+type Extent struct {
+	// Described track.
+	Track *Track
+	// First track sector in the file (the first index position).
+	Start int
+	// Number of the track sectors.
+	Sectors int
+	// Byte offset of the first sector in the file.
+	Offset int64
+	// Length of the track data in bytes.
+	Length int64
+}
+
+// Extents returns tracks data locations in the binary (BINARY or MOTOROLA)
+// file of the given size. Tracks sectors sizes are implied by their datatypes.
+func (f *File) Extents(size int64) ([]Extent, error) {
+	if !isRawFile(f) {
+		return nil, fmt.Errorf("%s file has no sectors layout", f.Type)
+	}
+
+	extents := make([]Extent, 0, len(f.Tracks))
+	for _, t := range f.Tracks {
+		start := -1
+		for _, idx := range t.Indexes {
+			if !idx.InPreviousFile {
+				start = idx.Time.TotalFrames()
+				break
+			}
+		}
+		if start < 0 {
+			return nil, fmt.Errorf("track %d has no indexes in the file", t.Number)
+		}
+		if t.DataType.SectorSize() == 0 {
+			return nil, fmt.Errorf("track %d has unknown datatype", t.Number)
+		}
+
+		e := Extent{Track: t, Start: start}
+		if l := len(extents); l > 0 {
+			prev := &extents[l-1]
+			prev.Sectors = start - prev.Start
+			if prev.Sectors < 0 {
+				return nil, fmt.Errorf("track %d starts before track %d", t.Number, prev.Track.Number)
+			}
+			prev.Length = int64(prev.Sectors * prev.Track.DataType.SectorSize())
+			e.Offset = prev.Offset + prev.Length
+		} else {
+			// Sectors before the first index belong to the first track datatype.
+			e.Offset = int64(start * t.DataType.SectorSize())
+		}
+		extents = append(extents, e)
+	}
+
+	if l := len(extents); l > 0 {
+		last := &extents[l-1]
+		if size < last.Offset {
+			return nil, fmt.Errorf("file size %d is less than track %d offset %d", size, last.Track.Number, last.Offset)
+		}
+		sectorSize := int64(last.Track.DataType.SectorSize())
+		last.Sectors = int((size - last.Offset) / sectorSize)
+		last.Length = int64(last.Sectors) * sectorSize
+	}
+
+	return extents, nil
+}
+
+// SectorReader reads sectors of the track from the binary file.
+type SectorReader struct {
+	r      io.ReaderAt
+	extent Extent
+	buf    []byte
+}
+
+// NewSectorReader returns reader of the track described by the extent.
+func NewSectorReader(r io.ReaderAt, extent Extent) *SectorReader {
+	return &SectorReader{
+		r:      r,
+		extent: extent,
+		buf:    make([]byte, extent.Track.DataType.SectorSize()),
+	}
+}
+
+// ReadSector returns raw sector with the given address. Address is the sector
+// position in the file, as INDEX commands use. The returned slice is valid
+// until the next read.
+func (sr *SectorReader) ReadSector(lba int) ([]byte, error) {
+	e := sr.extent
+	if lba < e.Start || lba >= e.Start+e.Sectors {
+		return nil, fmt.Errorf("sector %d is out of track %d", lba, e.Track.Number)
+	}
+
+	offset := e.Offset + int64(lba-e.Start)*int64(len(sr.buf))
+	// ReadAt may return io.EOF with the whole sector read at the end of file.
+	if n, err := sr.r.ReadAt(sr.buf, offset); n < len(sr.buf) {
+		return nil, errors.Wrapf(err, "failed to read sector %d", lba)
+	}
+
+	return sr.buf, nil
+}
+
+// ReadUserData returns user data of the sector with the given address:
+// sync pattern, header, subheader, EDC and ECC are stripped from the data
+// sectors, subcode is stripped from the CD+G sectors. The returned slice
+// is valid until the next read.
+func (sr *SectorReader) ReadUserData(lba int) ([]byte, error) {
+	sector, err := sr.ReadSector(lba)
+	if err != nil {
+		return nil, err
+	}
+
+	return userData(sr.extent.Track.DataType, sector)
+}
+
+// userData returns user data part of the sector of the given datatype.
+func userData(dataType TrackDataType, sector []byte) ([]byte, error) {
+	switch dataType {
+	case DataTypeAudio, DataTypeMode1_2048:
+		return sector, nil
+	case DataTypeCdg:
+		return sector[:rawSectorSize], nil
+	case DataTypeMode1_2352, DataTypeMode2_2352, DataTypeCdi_2352:
+		switch mode := sector[sectorHeaderSize-1]; mode {
+		case 1:
+			return sector[sectorHeaderSize : sectorHeaderSize+2048], nil
+		case 2:
+			return mode2UserData(sector[sectorHeaderSize:]), nil
+		default:
+			return nil, fmt.Errorf("unsupported sector mode %d", mode)
+		}
+	case DataTypeMode2_2336, DataTypeCdi_2336:
+		return mode2UserData(sector), nil
+	}
+
+	return nil, fmt.Errorf("unknown track datatype: %d", dataType)
+}
+
+// mode2UserData returns user data of the MODE2 sector starting with subheader.
+func mode2UserData(sector []byte) []byte {
+	data := sector[subheaderSize:]
+	if sector[2]&submodeForm2 != 0 {
+		return data[:2324]
+	}
+	return data[:2048]
+}
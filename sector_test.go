@@ -0,0 +1,124 @@
+package cue
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// rawSector returns raw data sector of the given mode filled with the fill byte.
+func rawSector(mode byte, form2 bool, fill byte) []byte {
+	sector := make([]byte, rawSectorSize)
+	copy(sector, []byte{0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0})
+	sector[15] = mode
+	data := sector[16:]
+	if mode == 2 {
+		if form2 {
+			data[2], data[6] = submodeForm2, submodeForm2
+		}
+		data = data[subheaderSize:]
+	}
+	for i := range data {
+		data[i] = fill
+	}
+	return sector
+}
+
+func TestSectorReader(t *testing.T) {
+	const input = `FILE "image.bin" BINARY
+  TRACK 01 MODE1/2352
+    INDEX 01 00:00:00
+  TRACK 02 MODE2/2352
+    INDEX 01 00:00:02
+  TRACK 03 AUDIO
+    INDEX 00 00:00:04
+    INDEX 01 00:00:05
+`
+	sheet, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	var image bytes.Buffer
+	image.Write(rawSector(1, false, 1))
+	image.Write(rawSector(1, false, 2))
+	image.Write(rawSector(2, false, 3))
+	image.Write(rawSector(2, true, 4))
+	image.Write(bytes.Repeat([]byte{5}, 3*rawSectorSize+100))
+
+	extents, err := sheet.Files[0].Extents(int64(image.Len()))
+	if err != nil {
+		t.Fatalf("Failed to calculate extents. %s", err.Error())
+	}
+	var expected = []struct {
+		start, sectors int
+		offset         int64
+	}{
+		{0, 2, 0},
+		{2, 2, 2 * rawSectorSize},
+		{4, 3, 4 * rawSectorSize},
+	}
+	for i, e := range extents {
+		if e.Start != expected[i].start || e.Sectors != expected[i].sectors || e.Offset != expected[i].offset {
+			t.Fatalf("track %d: unexpected extent %+v", e.Track.Number, e)
+		}
+	}
+
+	r := bytes.NewReader(image.Bytes())
+	var tests = []struct {
+		extent int
+		lba    int
+		size   int
+		fill   byte
+	}{
+		{0, 1, 2048, 2},
+		{1, 2, 2048, 3},
+		{1, 3, 2324, 4},
+		{2, 6, 2352, 5},
+	}
+	for _, tt := range tests {
+		data, err := NewSectorReader(r, extents[tt.extent]).ReadUserData(tt.lba)
+		if err != nil {
+			t.Fatalf("Failed to read sector %d. %s", tt.lba, err.Error())
+		}
+		if len(data) != tt.size || !bytes.Equal(data, bytes.Repeat([]byte{tt.fill}, tt.size)) {
+			t.Fatalf("sector %d: unexpected user data", tt.lba)
+		}
+	}
+
+	if _, err := NewSectorReader(r, extents[0]).ReadSector(2); err == nil {
+		t.Fatalf("sector of the other track read")
+	}
+}
+
+// eofReaderAt returns io.EOF with the data read up to the end.
+type eofReaderAt []byte
+
+func (r eofReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n := copy(p, r[off:])
+	if off+int64(n) == int64(len(r)) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func TestSectorReaderEOF(t *testing.T) {
+	sheet, err := Parse(strings.NewReader("FILE \"image.bin\" BINARY\n  TRACK 01 MODE1/2352\n    INDEX 01 00:00:00\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+	image := append(rawSector(1, false, 1), rawSector(1, false, 2)...)
+	extents, err := sheet.Files[0].Extents(int64(len(image)))
+	if err != nil {
+		t.Fatalf("Failed to calculate extents. %s", err.Error())
+	}
+
+	sr := NewSectorReader(eofReaderAt(image), extents[0])
+	if data, err := sr.ReadUserData(1); err != nil || data[0] != 2 {
+		t.Fatalf("Failed to read the last sector. %v", err)
+	}
+	if _, err := NewSectorReader(eofReaderAt(image[:len(image)-1]), extents[0]).ReadSector(1); err == nil {
+		t.Fatal("expected error of the truncated sector")
+	}
+}
@@ -0,0 +1,69 @@
+package cue
+
+const (
+	// Default lengths of the first session lead-out, other sessions
+	// lead-outs and sessions lead-ins in frames.
+	firstLeadOut  = 6750
+	nextLeadOut   = 2250
+	defaultLeadIn = 4500
+	// Length of the session first track pregap included into the gap
+	// between sessions.
+	sessionPregap = 150
+)
+
+// TrackSession returns number of the session containing track with
+// the given number. Single-session sheets have the only session 1.
+func (s *Sheet) TrackSession(number int) int {
+	session := 1
+	for _, ss := range s.Sessions {
+		if ss.FirstTrack <= number {
+			session = ss.Number
+		}
+	}
+	return session
+}
+
+// SessionTracks returns tracks of the session with the given number.
+func (s *Sheet) SessionTracks(number int) (tracks []*Track) {
+	for _, f := range s.Files {
+		for _, t := range f.Tracks {
+			if s.TrackSession(t.Number) == number {
+				tracks = append(tracks, t)
+			}
+		}
+	}
+	return tracks
+}
+
+// isSessionStart returns session started by the track with the given number
+// or nil if the track is not the first session track.
+func (s *Sheet) isSessionStart(number int) *Session {
+	for i := range s.Sessions {
+		if s.Sessions[i].FirstTrack == number {
+			return &s.Sessions[i]
+		}
+	}
+	return nil
+}
+
+// sessionGap returns number of frames between the end of the previous
+// session last track and the INDEX 01 of the session first track:
+// previous session lead-out, session lead-in and the first track pregap.
+// Default lengths are used for unspecified lead-in and lead-out.
+func (s *Sheet) sessionGap(number int) int {
+	if number < 2 || number > len(s.Sessions) {
+		return 0
+	}
+	leadOut := s.Sessions[number-2].LeadOut.TotalFrames()
+	if leadOut == 0 {
+		leadOut = nextLeadOut
+		if number == 2 {
+			leadOut = firstLeadOut
+		}
+	}
+	leadIn := s.Sessions[number-1].LeadIn.TotalFrames()
+	if leadIn == 0 {
+		leadIn = defaultLeadIn
+	}
+	return leadOut + leadIn + sessionPregap
+}
@@ -0,0 +1,113 @@
+package cue
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const enhancedCD = `REM GENRE Rock
+REM SESSION 01
+FILE "audio.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    INDEX 01 04:00:00
+REM LEAD-OUT 01:30:00
+REM SESSION 02
+REM LEAD-IN 01:00:00
+FILE "data.bin" BINARY
+  TRACK 03 MODE2/2352
+    INDEX 01 00:00:00
+`
+
+func TestParseSessions(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(enhancedCD))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	if len(sheet.Comments) != 1 || sheet.Comments[0] != "GENRE Rock" {
+		t.Fatalf("expected single GENRE comment but %v received", sheet.Comments)
+	}
+	expected := []Session{
+		{Number: 1, FirstTrack: 1, LeadOut: Time{1, 30, 0}},
+		{Number: 2, FirstTrack: 3, LeadIn: Time{1, 0, 0}},
+	}
+	if len(sheet.Sessions) != len(expected) {
+		t.Fatalf("expected %d sessions but %d received", len(expected), len(sheet.Sessions))
+	}
+	for i, s := range expected {
+		if sheet.Sessions[i] != s {
+			t.Fatalf("expected session %+v but %+v received", s, sheet.Sessions[i])
+		}
+	}
+	if n := sheet.TrackSession(2); n != 1 {
+		t.Fatalf("expected track 2 in session 1 but %d received", n)
+	}
+	if tracks := sheet.SessionTracks(2); len(tracks) != 1 || tracks[0].Number != 3 {
+		t.Fatalf("expected track 3 in session 2")
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, sheet); err != nil {
+		t.Fatalf("Failed to write sheet. %s", err.Error())
+	}
+	if buf.String() != enhancedCD {
+		t.Fatalf("expected\n%s\nbut\n%s\nreceived", enhancedCD, buf.String())
+	}
+	parsed, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("Failed to parse written sheet. %s", err.Error())
+	}
+	if len(parsed.Sessions) != 2 || parsed.Sessions[1] != sheet.Sessions[1] {
+		t.Fatalf("unexpected sessions %+v", parsed.Sessions)
+	}
+
+	for _, text := range []string{
+		"FILE \"a.wav\" WAVE\n  TRACK 01 AUDIO\nREM SESSION 01\n",
+		"REM SESSION 01\nREM SESSION 02\n",
+		"REM SESSION 01\nREM LEAD-OUT 1:2\n",
+	} {
+		if _, err := Parse(strings.NewReader(text)); err == nil {
+			t.Fatalf("expected error for %q", text)
+		}
+	}
+
+	// Invalid session numbers are comments.
+	for _, text := range []string{
+		"REM SESSION 02\n",
+		"REM SESSION x\n",
+		"REM SESSION 01\nREM SESSION 03\n",
+	} {
+		sheet, err := Parse(strings.NewReader(text))
+		if err != nil {
+			t.Fatalf("Failed to parse %q. %s", text, err.Error())
+		}
+		if len(sheet.Comments) != 1 || !strings.HasPrefix(sheet.Comments[0], "SESSION ") || len(sheet.Sessions) > 1 {
+			t.Fatalf("unexpected sheet of %q %+v", text, sheet)
+		}
+	}
+}
+
+func TestValidateSessions(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(enhancedCD), 480, 60)
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+	sheet.Files[0].Tracks[0].Pregap = Time{0, 2, 0}
+
+	if findings := Validate(sheet, ProfileEnhancedCD); len(findings) != 0 {
+		t.Fatalf("unexpected findings %v", findings)
+	}
+	rules := findingRules(Validate(sheet, ProfileRedBookAudio))
+	if rules != "audio-only,session-count" {
+		t.Fatalf("unexpected findings %s", rules)
+	}
+
+	sheet.Sessions = sheet.Sessions[:1]
+	rules = findingRules(Validate(sheet, ProfileEnhancedCD))
+	if rules != "session-content,session-content" {
+		t.Fatalf("unexpected findings %s", rules)
+	}
+}
@@ -1,84 +1,52 @@
 package cue
 
 import (
+	"fmt"
 	"path/filepath"
 	"strings"
 )
 
-const (
-	framesPerSecond = 75
-
-	// Intel binary file (least significant byte first)
-	FileTypeBinary FileType = iota
-	// Motorola binary file (most significant byte first)
-	FileTypeMotorola
-	// Audio AIFF file
-	FileTypeAiff
-	// Audio WAVE file
-	FileTypeWave
-	// Audio MP3 file
-	FileTypeMp3
-
-	// AUDIO – Audio/Music (2352)
-	DataTypeAudio = iota
-	// CDG – Karaoke CD+G (2448)
-	DataTypeCdg
-	// MODE1/2048 – CDROM Mode1 Data (cooked)
-	DataTypeMode1_2048
-	// MODE1/2352 – CDROM Mode1 Data (raw)
-	DataTypeMode1_2352
-	// MODE2/2336 – CDROM-XA Mode2 Data
-	DataTypeMode2_2336
-	// MODE2/2352 – CDROM-XA Mode2 Data
-	DataTypeMode2_2352
-	// CDI/2336 – CDI Mode2 Data
-	DataTypeCdi_2336
-	// CDI/2352 – CDI Mode2 Data
-	DataTypeCdi_2352
-
-	// Digital copy permitted.
-	TrackFlagDcp = iota
-	// Four channel audio.
-	TrackFlag4ch
-	// Pre-emphasis enabled (audio tracks only).
-	TrackFlagPre
-	// Serial copy management system (not supported by all recorders).
-	TrackFlagScms
-)
+const framesPerSecond = 75
 
 type (
 	// Cue sheet file representation.
 	Sheet struct {
 		// Disc's media catalog number.
-		Catalog string
+		Catalog string `json:"catalog,omitempty"`
 		// Name of a perfomer for a CD-TEXT enhanced disc
-		Performer string
+		Performer string `json:"performer,omitempty"`
 		// Specify a title for a CD-TEXT enhanced disc.
-		Title string
+		Title string `json:"title,omitempty"`
 		// Specify songwriter for disc.
-		Songwriter string
+		Songwriter string `json:"songwriter,omitempty"`
 		// Comments in the CUE SHEET file.
-		Comments []string
+		Comments []string `json:"comments,omitempty"`
 		// Name of the file that contains the encoded CD-TEXT information for the disc.
-		CdTextFile string
+		CdTextFile string `json:"cdTextFile,omitempty"`
 		// Data/audio files descibed byt the cue-file.
-		Files []*File
+		Files []*File `json:"files"`
+		// Disc sessions (REM SESSION commands), empty for single-session sheets.
+		Sessions []Session `json:"sessions,omitempty"`
 	}
 
-	// Track datatype.
-	TrackDataType int
-
-	// Type of the audio file.
-	FileType int
-
-	// Additional decode information about track.
-	TrackFlag int
+	// Disc session of the multi-session (e.g. Enhanced CD) sheet.
+	Session struct {
+		// Session number.
+		Number int `json:"number"`
+		// Number of the first session track, the session lasts up to
+		// the next session first track.
+		FirstTrack int `json:"firstTrack"`
+		// Length of the session lead-in (REM LEAD-IN), zero if not specified.
+		LeadIn Time `json:"leadIn"`
+		// Length of the session lead-out (REM LEAD-OUT), zero if not specified.
+		LeadOut Time `json:"leadOut"`
+	}
 
 	// Time point description type.
 	Time struct {
 		// Minutes.
 		Min int
-		// Minutes.
+		// Seconds.
 		Sec int
 		// Frames.
 		Frames int
@@ -87,46 +55,54 @@ type (
 	// Track index type
 	Index struct {
 		// Index number.
-		Number int
+		Number int `json:"number"`
 		// Index starting time.
-		Time Time
+		Time Time `json:"time"`
+		// Index is located at the end of the previous file
+		// (gaps appended layout).
+		InPreviousFile bool `json:"inPreviousFile,omitempty"`
 	}
 
 	Track struct {
+		// Stable track identity (e.g. UUID) which survives renumbering,
+		// used by Merge to match tracks. Not stored in the cue sheet.
+		ID string `json:"id,omitempty"`
 		// Track number (1-99).
-		Number int
+		Number int `json:"number"`
 		// Track datatype.
-		DataType TrackDataType
+		DataType TrackDataType `json:"dataType"`
 		// Track title.
-		Title string
+		Title string `json:"title,omitempty"`
 		// Track preformer.
-		Performer string
+		Performer string `json:"performer,omitempty"`
 		// Songwriter.
-		Songwriter string
+		Songwriter string `json:"songwriter,omitempty"`
 		// Track decode flags.
-		Flags []TrackFlag
+		Flags []TrackFlag `json:"flags,omitempty"`
 		// Internetional Standaard Recording Code.
-		Isrc string
+		Isrc string `json:"isrc,omitempty"`
+		// Comments (REM commands) following the TRACK command.
+		Comments []string `json:"comments,omitempty"`
 		// Track indexes.
-		Indexes []Index
+		Indexes []Index `json:"indexes"`
 		// Length of the track pregap.
-		Pregap Time
+		Pregap Time `json:"pregap"`
 		// Length of the track postgap.
-		Postgap       Time
-		StartPosition float64
-		EndPosition   float64
+		Postgap       Time    `json:"postgap"`
+		StartPosition float64 `json:"startPosition"`
+		EndPosition   float64 `json:"endPosition"`
 	}
 
 	// Audio file representation structure.
 	File struct {
 		// Name (path) of the file.
-		Name string
+		Name string `json:"name"`
 		// Type of the audio file.
-		Type FileType
+		Type FileType `json:"type"`
 		// List of present tracks in the file.
-		Tracks []*Track
+		Tracks []*Track `json:"tracks"`
 		// Total duration in seconds
-		Duration float64
+		Duration float64 `json:"duration,omitempty"`
 	}
 )
 
@@ -135,12 +111,31 @@ func (time Time) Seconds() float64 {
 	return float64(time.Min*60) + float64(time.Sec) + float64(time.Frames)/framesPerSecond
 }
 
+// String returns time in the mm:ss:ff form.
+func (time Time) String() string {
+	return fmt.Sprintf("%02d:%02d:%02d", time.Min, time.Sec, time.Frames)
+}
+
+// TotalFrames returns time point as a number of frames.
+func (time Time) TotalFrames() int {
+	return (time.Min*60+time.Sec)*framesPerSecond + time.Frames
+}
+
+// TimeFromFrames converts number of frames into Time.
+func TimeFromFrames(frames int) Time {
+	return Time{
+		Min:    frames / (60 * framesPerSecond),
+		Sec:    frames / framesPerSecond % 60,
+		Frames: frames % framesPerSecond,
+	}
+}
+
 // StartTime return track start time
 func (t *Track) StartTime() (time Time) {
-	if len(t.Indexes) == 1 {
-		time = t.Indexes[0].Time
-	} else if len(t.Indexes) > 1 {
-		time = t.Indexes[1].Time
+	for i, idx := range t.Indexes {
+		if i == 0 || idx.Number == 1 {
+			time = idx.Time
+		}
 	}
 	return
 }
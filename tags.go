@@ -0,0 +1,363 @@
+package cue
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+const (
+	// FLAC VORBIS_COMMENT metadata block type.
+	flacVorbisComment = 4
+	// Name of the tag field with embedded cue-sheet.
+	cueSheetTag = "CUESHEET"
+	// Vendor string of the created Vorbis comments.
+	vorbisVendor = "cue-go"
+
+	// APEv2 tag constants.
+	apePreamble      = "APETAGEX"
+	apeVersion       = 2000
+	apeHeaderSize    = 32
+	apeHasHeader     = 1 << 31
+	apeIsHeader      = 1 << 29
+	apeItemTypeMask  = 3 << 1
+	id3v1Size        = 128
+	id3v1Identifier  = "TAG"
+	apeMaxItemsCount = 65536
+)
+
+// DecodeText converts cue-sheet text (file or embedded) into UTF-8 string.
+// UTF-8 and UTF-16 texts with BOM, UTF-8 texts and Windows-1252 texts are
+// supported.
+func DecodeText(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xef, 0xbb, 0xbf}):
+		return string(data[3:]), nil
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}), bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		dec := unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder()
+		text, err := dec.Bytes(data)
+		return string(text), errors.Wrap(err, "failed to decode UTF-16 text")
+	case utf8.Valid(data):
+		return string(data), nil
+	}
+
+	text, err := charmap.Windows1252.NewDecoder().Bytes(data)
+	return string(text), errors.Wrap(err, "failed to decode Windows-1252 text")
+}
+
+// parseEmbedded parses embedded cue-sheet text.
+func parseEmbedded(data []byte, durations ...float64) (*Sheet, error) {
+	text, err := DecodeText(data)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(strings.NewReader(text), durations...)
+}
+
+// vorbisComment is the Vorbis comment structure.
+type vorbisComment struct {
+	vendor string
+	fields []string
+}
+
+// parseVorbisComment parses Vorbis comment data.
+func parseVorbisComment(data []byte) (vc vorbisComment, err error) {
+	r := bytes.NewReader(data)
+	readString := func() (string, error) {
+		var l uint32
+		if err := binary.Read(r, binary.LittleEndian, &l); err != nil {
+			return "", err
+		}
+		if int64(l) > int64(r.Len()) {
+			return "", errors.New("string is out of data")
+		}
+		buf := make([]byte, l)
+		_, err := io.ReadFull(r, buf)
+		return string(buf), err
+	}
+
+	if vc.vendor, err = readString(); err != nil {
+		return vc, errors.Wrap(err, "failed to read Vorbis comment vendor")
+	}
+	var count uint32
+	if err = binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return vc, errors.Wrap(err, "failed to read Vorbis comment fields count")
+	}
+	for i := uint32(0); i < count; i++ {
+		field, err := readString()
+		if err != nil {
+			return vc, errors.Wrap(err, "failed to read Vorbis comment field")
+		}
+		vc.fields = append(vc.fields, field)
+	}
+
+	return vc, nil
+}
+
+// bytes returns encoded Vorbis comment.
+func (vc vorbisComment) bytes() []byte {
+	var buf bytes.Buffer
+	writeString := func(s string) {
+		binary.Write(&buf, binary.LittleEndian, uint32(len(s)))
+		buf.WriteString(s)
+	}
+	writeString(vc.vendor)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(vc.fields)))
+	for _, f := range vc.fields {
+		writeString(f)
+	}
+	return buf.Bytes()
+}
+
+// get returns value of the first field with the given name.
+func (vc vorbisComment) get(name string) (string, bool) {
+	for _, f := range vc.fields {
+		if i := strings.IndexByte(f, '='); i >= 0 && strings.EqualFold(f[:i], name) {
+			return f[i+1:], true
+		}
+	}
+	return "", false
+}
+
+// set replaces all fields with the given name by the single field.
+func (vc *vorbisComment) set(name, value string) {
+	fields := vc.fields[:0]
+	for _, f := range vc.fields {
+		if i := strings.IndexByte(f, '='); i < 0 || !strings.EqualFold(f[:i], name) {
+			fields = append(fields, f)
+		}
+	}
+	vc.fields = append(fields, name+"="+value)
+}
+
+// ReadVorbisCueSheet reads sheet embedded into the CUESHEET field of the
+// FLAC stream Vorbis comment. File duration is taken from the stream.
+func ReadVorbisCueSheet(r io.Reader) (*Sheet, error) {
+	blocks, err := readFLACMetadata(r)
+	if err != nil {
+		return nil, err
+	}
+	sampleRate, totalSamples, err := flacStreamParams(blocks)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range blocks {
+		if b.typ != flacVorbisComment {
+			continue
+		}
+		vc, err := parseVorbisComment(b.data)
+		if err != nil {
+			return nil, err
+		}
+		if text, ok := vc.get(cueSheetTag); ok {
+			var durations []float64
+			if sampleRate > 0 {
+				durations = append(durations, float64(totalSamples)/float64(sampleRate))
+			}
+			return parseEmbedded([]byte(text), durations...)
+		}
+	}
+
+	return nil, errors.New("FLAC stream has no CUESHEET Vorbis comment")
+}
+
+// WriteVorbisCueSheet copies FLAC stream from r to w replacing the CUESHEET
+// field of its Vorbis comment with the sheet. Other fields are preserved,
+// audio frames are not re-encoded.
+func WriteVorbisCueSheet(w io.Writer, r io.Reader, sheet *Sheet) error {
+	blocks, err := readFLACMetadata(r)
+	if err != nil {
+		return err
+	}
+
+	var text bytes.Buffer
+	if err := Write(&text, sheet); err != nil {
+		return err
+	}
+
+	vc := vorbisComment{vendor: vorbisVendor}
+	for _, b := range blocks {
+		if b.typ == flacVorbisComment {
+			if vc, err = parseVorbisComment(b.data); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	vc.set(cueSheetTag, text.String())
+
+	blocks = replaceFLACBlock(blocks, flacBlock{flacVorbisComment, vc.bytes()})
+	if err := writeFLACMetadata(w, blocks); err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// apeItem is the APEv2 tag item.
+type apeItem struct {
+	flags uint32
+	key   string
+	value []byte
+}
+
+// apeTag describes APEv2 tag location and items.
+type apeTag struct {
+	// Tag start (header or first item) offset and end offset.
+	start int64
+	end   int64
+	items []apeItem
+}
+
+// readAPETag reads APEv2 tag at the end of the file (before ID3v1 tag).
+// Returns tag with start == end if file has no APE tag.
+func readAPETag(r io.ReaderAt, size int64) (tag apeTag, err error) {
+	tag.start, tag.end = size, size
+	buf := make([]byte, apeHeaderSize)
+	if size >= id3v1Size {
+		if _, err = r.ReadAt(buf[:3], size-id3v1Size); err != nil {
+			return tag, errors.Wrap(err, "failed to read ID3v1 tag")
+		}
+		if string(buf[:3]) == id3v1Identifier {
+			tag.start, tag.end = size-id3v1Size, size-id3v1Size
+		}
+	}
+	if tag.end < apeHeaderSize {
+		return tag, nil
+	}
+
+	if _, err = r.ReadAt(buf, tag.end-apeHeaderSize); err != nil {
+		return tag, errors.Wrap(err, "failed to read APE tag footer")
+	}
+	if string(buf[:8]) != apePreamble {
+		return tag, nil
+	}
+	tagSize := int64(binary.LittleEndian.Uint32(buf[12:]))
+	count := binary.LittleEndian.Uint32(buf[16:])
+	flags := binary.LittleEndian.Uint32(buf[20:])
+	itemsStart := tag.end - tagSize
+	if tagSize < apeHeaderSize || itemsStart < 0 || count > apeMaxItemsCount {
+		return tag, errors.New("illegal APE tag footer")
+	}
+	tag.start = itemsStart
+	if flags&apeHasHeader != 0 {
+		tag.start -= apeHeaderSize
+	}
+
+	data := make([]byte, tagSize-apeHeaderSize)
+	if _, err = r.ReadAt(data, itemsStart); err != nil {
+		return tag, errors.Wrap(err, "failed to read APE tag items")
+	}
+	for i := uint32(0); i < count; i++ {
+		if len(data) < 8 {
+			return tag, errors.New("APE tag item is out of tag")
+		}
+		l := binary.LittleEndian.Uint32(data)
+		item := apeItem{flags: binary.LittleEndian.Uint32(data[4:])}
+		data = data[8:]
+		k := bytes.IndexByte(data, 0)
+		if k < 0 || uint64(len(data)-k-1) < uint64(l) {
+			return tag, errors.New("APE tag item is out of tag")
+		}
+		item.key = string(data[:k])
+		item.value = data[k+1 : k+1+int(l)]
+		data = data[k+1+int(l):]
+		tag.items = append(tag.items, item)
+	}
+
+	return tag, nil
+}
+
+// apeFrame returns APEv2 tag header or footer.
+func apeFrame(size, count int, isHeader bool) []byte {
+	buf := make([]byte, apeHeaderSize)
+	copy(buf, apePreamble)
+	binary.LittleEndian.PutUint32(buf[8:], apeVersion)
+	binary.LittleEndian.PutUint32(buf[12:], uint32(size))
+	binary.LittleEndian.PutUint32(buf[16:], uint32(count))
+	flags := uint32(apeHasHeader)
+	if isHeader {
+		flags |= apeIsHeader
+	}
+	binary.LittleEndian.PutUint32(buf[20:], flags)
+	return buf
+}
+
+// bytes returns encoded APEv2 tag with header and footer.
+func (tag apeTag) bytes() []byte {
+	var items bytes.Buffer
+	for _, item := range tag.items {
+		binary.Write(&items, binary.LittleEndian, uint32(len(item.value)))
+		binary.Write(&items, binary.LittleEndian, item.flags)
+		items.WriteString(item.key)
+		items.WriteByte(0)
+		items.Write(item.value)
+	}
+
+	size := items.Len() + apeHeaderSize
+	var buf bytes.Buffer
+	buf.Write(apeFrame(size, len(tag.items), true))
+	buf.Write(items.Bytes())
+	buf.Write(apeFrame(size, len(tag.items), false))
+	return buf.Bytes()
+}
+
+// ReadAPECueSheet reads sheet embedded into the Cuesheet item of the APEv2
+// tag of the file with the given size.
+func ReadAPECueSheet(r io.ReaderAt, size int64) (*Sheet, error) {
+	tag, err := readAPETag(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range tag.items {
+		if strings.EqualFold(item.key, cueSheetTag) {
+			if item.flags&apeItemTypeMask != 0 {
+				return nil, fmt.Errorf("APE tag item %s is not a text", item.key)
+			}
+			return parseEmbedded(item.value)
+		}
+	}
+
+	return nil, errors.New("file has no Cuesheet APE tag item")
+}
+
+// WriteAPECueSheet copies the file with the given size from r to w replacing
+// the Cuesheet item of its APEv2 tag with the sheet. Tag is created if
+// the file has no one, other items and ID3v1 tag are preserved.
+func WriteAPECueSheet(w io.Writer, r io.ReaderAt, size int64, sheet *Sheet) error {
+	tag, err := readAPETag(r, size)
+	if err != nil {
+		return err
+	}
+
+	var text bytes.Buffer
+	if err := Write(&text, sheet); err != nil {
+		return err
+	}
+
+	items := tag.items[:0]
+	for _, item := range tag.items {
+		if !strings.EqualFold(item.key, cueSheetTag) {
+			items = append(items, item)
+		}
+	}
+	tag.items = append(items, apeItem{key: "Cuesheet", value: text.Bytes()})
+
+	if _, err := io.Copy(w, io.NewSectionReader(r, 0, tag.start)); err != nil {
+		return err
+	}
+	if _, err := w.Write(tag.bytes()); err != nil {
+		return err
+	}
+	_, err = io.Copy(w, io.NewSectionReader(r, tag.end, size-tag.end))
+	return err
+}
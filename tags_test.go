@@ -0,0 +1,124 @@
+package cue
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDecodeText(t *testing.T) {
+	var tests = []struct {
+		input []byte
+		text  string
+	}{
+		{[]byte("TITLE \"Caf\xc3\xa9\""), "TITLE \"Café\""},
+		{[]byte("\xef\xbb\xbfTITLE"), "TITLE"},
+		{[]byte("\xff\xfeT\x00\xe9\x00"), "Té"},
+		{[]byte("\xfe\xff\x00T\x00\xe9"), "Té"},
+		{[]byte("Caf\xe9"), "Café"},
+	}
+
+	for _, tt := range tests {
+		text, err := DecodeText(tt.input)
+		if err != nil {
+			t.Fatalf("Failed to decode % x. %s", tt.input, err.Error())
+		}
+		if text != tt.text {
+			t.Fatalf("expected '%s' but '%s' decoded", tt.text, text)
+		}
+	}
+}
+
+const embeddedSheet = `PERFORMER "Doro"
+FILE "Doro - Doro.ape" WAVE
+  TRACK 01 AUDIO
+    TITLE "Unholy Love"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "I Had Too Much to Dream"
+    INDEX 01 04:31:07
+`
+
+func TestVorbisCueSheet(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(embeddedSheet))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	vc := vorbisComment{vendor: "test", fields: []string{"ARTIST=Doro", "cuesheet=old"}}
+	var stream bytes.Buffer
+	writeFLACMetadata(&stream, []flacBlock{
+		streamInfo(44100, 44100*600),
+		{flacVorbisComment, vc.bytes()},
+	})
+	stream.WriteString("audio")
+
+	var out bytes.Buffer
+	if err := WriteVorbisCueSheet(&out, &stream, sheet); err != nil {
+		t.Fatalf("Failed to write sheet. %s", err.Error())
+	}
+	if !bytes.HasSuffix(out.Bytes(), []byte("audio")) {
+		t.Fatalf("audio frames are not preserved")
+	}
+
+	blocks, _ := readFLACMetadata(bytes.NewReader(out.Bytes()))
+	written, _ := parseVorbisComment(blocks[1].data)
+	if written.vendor != "test" || len(written.fields) != 2 || written.fields[0] != "ARTIST=Doro" {
+		t.Fatalf("unexpected Vorbis comment %+v", written)
+	}
+
+	decoded, err := ReadVorbisCueSheet(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("Failed to read sheet. %s", err.Error())
+	}
+	if decoded.Files[0].Duration != 600 || decoded.Files[0].Tracks[1].Title != "I Had Too Much to Dream" {
+		t.Fatalf("unexpected sheet %+v", decoded.Files[0])
+	}
+}
+
+func TestAPECueSheet(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(embeddedSheet))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	id3 := append([]byte("TAG"), make([]byte, id3v1Size-3)...)
+	var tests = [][]byte{
+		[]byte("MAC audio"),
+		append([]byte("MAC audio"), id3...),
+		append(append([]byte("MAC audio"), apeTag{items: []apeItem{
+			{0, "Artist", []byte("Doro")},
+			{0, "CUESHEET", []byte("TITLE \"Old\"")},
+		}}.bytes()...), id3...),
+	}
+
+	for _, input := range tests {
+		var out bytes.Buffer
+		if err := WriteAPECueSheet(&out, bytes.NewReader(input), int64(len(input)), sheet); err != nil {
+			t.Fatalf("Failed to write sheet. %s", err.Error())
+		}
+		data := out.Bytes()
+		if !bytes.HasPrefix(data, []byte("MAC audio")) {
+			t.Fatalf("audio data is not preserved")
+		}
+		if bytes.HasSuffix(input, id3) != bytes.HasSuffix(data, id3) {
+			t.Fatalf("ID3v1 tag is not preserved")
+		}
+
+		tag, err := readAPETag(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("Failed to read APE tag. %s", err.Error())
+		}
+		if last := tag.items[len(tag.items)-1]; last.key != "Cuesheet" || len(tag.items) > 2 {
+			t.Fatalf("unexpected APE tag items %+v", tag.items)
+		}
+
+		decoded, err := ReadAPECueSheet(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("Failed to read sheet. %s", err.Error())
+		}
+		if decoded.Performer != "Doro" || decoded.TracksCount() != 2 {
+			t.Fatalf("unexpected sheet %+v", decoded)
+		}
+	}
+}
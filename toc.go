@@ -0,0 +1,650 @@
+package cue
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// tocModes used for cdrdao TRACK modes and TrackDataType correspondence.
+var tocModes = map[string]TrackDataType{
+	"AUDIO":          DataTypeAudio,
+	"MODE1":          DataTypeMode1_2048,
+	"MODE1_RAW":      DataTypeMode1_2352,
+	"MODE2":          DataTypeMode2_2336,
+	"MODE2_RAW":      DataTypeMode2_2352,
+	"MODE2_FORM_MIX": DataTypeCdi_2336,
+}
+
+// samplesPerFrame is the number of CD audio samples in one frame.
+const samplesPerFrame = 44100 / framesPerSecond
+
+// tocLexer splits cdrdao TOC file into tokens.
+type tocLexer struct {
+	tokens []string
+	// Tokens which are quoted strings.
+	quoted map[int]bool
+	pos    int
+}
+
+// newTOCLexer splits data into words, quoted strings and braces.
+// Comments started with // are skipped.
+func newTOCLexer(data string) (*tocLexer, error) {
+	l := &tocLexer{quoted: make(map[int]bool)}
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case strings.HasPrefix(data[i:], "//"):
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+		case c == '{' || c == '}':
+			l.tokens = append(l.tokens, string(c))
+			i++
+		case c == '"':
+			var s bytes.Buffer
+			for i++; ; i++ {
+				if i >= len(data) {
+					return nil, errors.New("unterminated string")
+				}
+				if data[i] == '"' {
+					i++
+					break
+				}
+				if data[i] == '\\' && i+1 < len(data) {
+					i++
+					switch data[i] {
+					case 'n':
+						s.WriteByte('\n')
+						continue
+					case 't':
+						s.WriteByte('\t')
+						continue
+					}
+					// Octal character code.
+					if i+2 < len(data) {
+						if v, err := strconv.ParseUint(data[i:i+3], 8, 8); err == nil {
+							s.WriteByte(byte(v))
+							i += 2
+							continue
+						}
+					}
+				}
+				s.WriteByte(data[i])
+			}
+			l.quoted[len(l.tokens)] = true
+			l.tokens = append(l.tokens, s.String())
+		default:
+			start := i
+			for i < len(data) && !unicode.IsSpace(rune(data[i])) && !strings.ContainsRune(`{}"`, rune(data[i])) {
+				i++
+			}
+			l.tokens = append(l.tokens, data[start:i])
+		}
+	}
+	return l, nil
+}
+
+// peek returns the next token without consuming it.
+func (l *tocLexer) peek() string {
+	if l.pos < len(l.tokens) {
+		return l.tokens[l.pos]
+	}
+	return ""
+}
+
+// next returns the next token.
+func (l *tocLexer) next() (string, error) {
+	if l.pos >= len(l.tokens) {
+		return "", io.ErrUnexpectedEOF
+	}
+	l.pos++
+	return l.tokens[l.pos-1], nil
+}
+
+// string returns the next token which should be quoted string.
+func (l *tocLexer) string() (string, error) {
+	if !l.quoted[l.pos] {
+		return "", fmt.Errorf("string expected but '%s' found", l.peek())
+	}
+	return l.next()
+}
+
+// expect consumes the next token which should be equal to s.
+func (l *tocLexer) expect(s string) error {
+	t, err := l.next()
+	if err == nil && t != s {
+		err = fmt.Errorf("'%s' expected but '%s' found", s, t)
+	}
+	return err
+}
+
+// skipBlock skips tokens up to the closing brace of the block
+// which opening brace is the next token.
+func (l *tocLexer) skipBlock() error {
+	if err := l.expect("{"); err != nil {
+		return err
+	}
+	for depth := 1; depth > 0; {
+		t, err := l.next()
+		if err != nil {
+			return err
+		}
+		if !l.quoted[l.pos-1] {
+			switch t {
+			case "{":
+				depth++
+			case "}":
+				depth--
+			}
+		}
+	}
+	return nil
+}
+
+// isTOCTime returns true if token is time or samples value.
+func isTOCTime(s string) bool {
+	return s != "" && (s[0] >= '0' && s[0] <= '9')
+}
+
+// parseTOCTime returns number of frames in m:s:f time or samples value.
+// Plain numbers are samples for the audio tracks and bytes for the data tracks.
+func parseTOCTime(s string, dataType TrackDataType) (int, error) {
+	if strings.Contains(s, ":") {
+		min, sec, frames, err := parseTime(s)
+		if err != nil {
+			return 0, err
+		}
+		return Time{min, sec, frames}.TotalFrames(), nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to parse time %s", s)
+	}
+	if dataType.IsAudio() {
+		return v / samplesPerFrame, nil
+	}
+	return v / dataType.SectorSize(), nil
+}
+
+// parseTOCText parses CD_TEXT block, only the first language is used.
+func parseTOCText(l *tocLexer, title, performer, songwriter *string) error {
+	if err := l.expect("{"); err != nil {
+		return err
+	}
+	first := true
+	for {
+		t, err := l.next()
+		if err != nil {
+			return err
+		}
+		switch t {
+		case "}":
+			return nil
+		case "LANGUAGE_MAP":
+			if err := l.skipBlock(); err != nil {
+				return err
+			}
+			continue
+		case "LANGUAGE":
+		default:
+			return fmt.Errorf("unexpected CD_TEXT token '%s'", t)
+		}
+
+		if _, err := l.next(); err != nil {
+			return err
+		}
+		if err := l.expect("{"); err != nil {
+			return err
+		}
+		for l.peek() != "}" {
+			key, err := l.next()
+			if err != nil {
+				return err
+			}
+			if l.peek() == "{" {
+				// Binary data item.
+				if err := l.skipBlock(); err != nil {
+					return err
+				}
+				continue
+			}
+			value, err := l.string()
+			if err != nil {
+				return err
+			}
+			if !first {
+				continue
+			}
+			switch key {
+			case "TITLE":
+				*title = value
+			case "PERFORMER":
+				*performer = value
+			case "SONGWRITER":
+				*songwriter = value
+			}
+		}
+		l.next()
+		first = false
+	}
+}
+
+// tocFile is the position of the data in the cue FILE.
+type tocFile struct {
+	file *File
+	// Start sector and byte offset of the last track and its sector size.
+	sector     int
+	offset     int64
+	sectorSize int
+}
+
+// ParseTOC parses cdrdao TOC file and returns filled Sheet struct.
+// Tracks composed of several files are not supported.
+func ParseTOC(reader io.Reader) (*Sheet, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	l, err := newTOCLexer(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	sheet := new(Sheet)
+	var (
+		track   *Track
+		tracks  int
+		current *tocFile
+		// Track data position in the file, START and INDEX positions.
+		fstart   int
+		start    int
+		hasData  bool
+		hasStart bool
+		indexes  []int
+	)
+
+	// finishTrack converts positions of the current track into indexes.
+	finishTrack := func() error {
+		if track == nil {
+			return nil
+		}
+		if !hasData {
+			return fmt.Errorf("track %d has no data", track.Number)
+		}
+		if hasStart && start > 0 {
+			track.Indexes = append(track.Indexes, Index{Number: 0, Time: TimeFromFrames(fstart)})
+		}
+		track.Indexes = append(track.Indexes, Index{Number: 1, Time: TimeFromFrames(fstart + start)})
+		for i, pos := range indexes {
+			track.Indexes = append(track.Indexes, Index{Number: i + 2, Time: TimeFromFrames(fstart + start + pos)})
+		}
+		return nil
+	}
+
+	for l.peek() != "" {
+		t, _ := l.next()
+		var err error
+		switch t {
+		case "CD_DA", "CD_ROM", "CD_ROM_XA", "CD_I":
+		case "CATALOG":
+			sheet.Catalog, err = l.string()
+		case "CD_TEXT":
+			if track == nil {
+				err = parseTOCText(l, &sheet.Title, &sheet.Performer, &sheet.Songwriter)
+			} else {
+				err = parseTOCText(l, &track.Title, &track.Performer, &track.Songwriter)
+			}
+		case "TRACK":
+			if err = finishTrack(); err != nil {
+				break
+			}
+			var mode string
+			if mode, err = l.next(); err != nil {
+				break
+			}
+			dataType, ok := tocModes[mode]
+			if !ok {
+				err = fmt.Errorf("unsupported track mode %s", mode)
+				break
+			}
+			// Subchannel mode is ignored.
+			if s := l.peek(); s == "RW" || s == "RW_RAW" {
+				l.next()
+			}
+			track = &Track{Number: tracks + 1, DataType: dataType}
+			tracks++
+			fstart, start, hasData, hasStart, indexes = 0, 0, false, false, nil
+		default:
+			if track == nil {
+				err = fmt.Errorf("unexpected token '%s'", t)
+				break
+			}
+			err = parseTOCTrackStatement(l, t, sheet, track, &current, &fstart, &start, &hasData, &hasStart, &indexes)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "token %d", l.pos)
+		}
+	}
+	if err := finishTrack(); err != nil {
+		return nil, err
+	}
+
+	setPositions(sheet)
+	return sheet, nil
+}
+
+// parseTOCTrackStatement parses statement of the track.
+func parseTOCTrackStatement(l *tocLexer, t string, sheet *Sheet, track *Track, current **tocFile,
+	fstart, start *int, hasData, hasStart *bool, indexes *[]int) error {
+
+	setFlag := func(flag TrackFlag, on bool) {
+		for i, f := range track.Flags {
+			if f == flag {
+				track.Flags = append(track.Flags[:i], track.Flags[i+1:]...)
+				break
+			}
+		}
+		if on {
+			track.Flags = append(track.Flags, flag)
+		}
+	}
+	readTime := func() (int, error) {
+		s, err := l.next()
+		if err != nil {
+			return 0, err
+		}
+		return parseTOCTime(s, track.DataType)
+	}
+
+	var err error
+	switch t {
+	case "NO":
+		var flag string
+		if flag, err = l.next(); err != nil {
+			return err
+		}
+		switch flag {
+		case "COPY":
+			setFlag(TrackFlagDcp, false)
+		case "PRE_EMPHASIS":
+			setFlag(TrackFlagPre, false)
+		default:
+			return fmt.Errorf("unexpected NO %s statement", flag)
+		}
+	case "COPY":
+		setFlag(TrackFlagDcp, true)
+	case "PRE_EMPHASIS":
+		setFlag(TrackFlagPre, true)
+	case "TWO_CHANNEL_AUDIO":
+		setFlag(TrackFlag4ch, false)
+	case "FOUR_CHANNEL_AUDIO":
+		setFlag(TrackFlag4ch, true)
+	case "ISRC":
+		var isrc string
+		if isrc, err = l.string(); err != nil {
+			return err
+		}
+		code, err := ParseISRC(isrc)
+		if err != nil {
+			return err
+		}
+		track.Isrc = code.String()
+	case "PREGAP":
+		var frames int
+		if frames, err = readTime(); err != nil {
+			return err
+		}
+		track.Pregap = TimeFromFrames(frames)
+	case "SILENCE", "ZERO":
+		if t == "ZERO" && !isTOCTime(l.peek()) {
+			l.next()
+		}
+		var frames int
+		if frames, err = readTime(); err != nil {
+			return err
+		}
+		// Silence isn't stored in the files, so it becomes a gap.
+		if *hasData {
+			track.Postgap = TimeFromFrames(track.Postgap.TotalFrames() + frames)
+		} else {
+			track.Pregap = TimeFromFrames(track.Pregap.TotalFrames() + frames)
+		}
+	case "FILE", "AUDIOFILE", "DATAFILE":
+		if *hasData {
+			return fmt.Errorf("track %d: tracks composed of several files are not supported", track.Number)
+		}
+		var name string
+		if name, err = l.string(); err != nil {
+			return err
+		}
+		// Raw audio is big-endian unless swapped.
+		swap := l.peek() == "SWAP"
+		if swap {
+			l.next()
+		}
+		if *current == nil || (*current).file.Name != name {
+			f := &File{Name: name, Type: tocFileType(name, track.DataType)}
+			if swap && f.Type == FileTypeMotorola {
+				f.Type = FileTypeBinary
+			}
+			sheet.Files = append(sheet.Files, f)
+			*current = &tocFile{file: f, sector: -1}
+		}
+		(*current).file.Tracks = append((*current).file.Tracks, track)
+
+		var offset int64 = -1
+		if s := l.peek(); strings.HasPrefix(s, "#") {
+			l.next()
+			if offset, err = strconv.ParseInt(s[1:], 10, 64); err != nil {
+				return errors.Wrap(err, "failed to parse byte offset")
+			}
+		}
+		pos := 0
+		if t != "DATAFILE" {
+			if pos, err = readTime(); err != nil {
+				return err
+			}
+		}
+		// Length is not used, tracks are continuous in the file.
+		if isTOCTime(l.peek()) {
+			l.next()
+		}
+
+		cf := *current
+		sectorSize := track.DataType.SectorSize()
+		switch {
+		case offset >= 0 && cf.sector >= 0:
+			pos += cf.sector + int((offset-cf.offset)/int64(cf.sectorSize))
+		case offset >= 0:
+			pos += int(offset / int64(sectorSize))
+		}
+		if offset < 0 {
+			offset = int64(pos * sectorSize)
+		}
+		cf.sector, cf.offset, cf.sectorSize = pos, offset, sectorSize
+		*fstart = pos
+		*hasData = true
+	case "START":
+		*hasStart = true
+		if isTOCTime(l.peek()) {
+			if *start, err = readTime(); err != nil {
+				return err
+			}
+		}
+	case "INDEX":
+		var pos int
+		if pos, err = readTime(); err != nil {
+			return err
+		}
+		*indexes = append(*indexes, pos)
+	default:
+		return fmt.Errorf("unexpected token '%s'", t)
+	}
+	return nil
+}
+
+// tocFileType returns cue file type of the TOC file.
+// cdrdao raw audio files are big-endian.
+func tocFileType(name string, dataType TrackDataType) FileType {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".wav":
+		return FileTypeWave
+	case ".mp3":
+		return FileTypeMp3
+	case ".aif", ".aiff":
+		return FileTypeAiff
+	}
+	if dataType.IsAudio() {
+		return FileTypeMotorola
+	}
+	return FileTypeBinary
+}
+
+// WriteTOC writes the sheet in the cdrdao TOC format.
+func WriteTOC(w io.Writer, sheet *Sheet) error {
+	var buf bytes.Buffer
+
+	session := "CD_DA"
+	for _, f := range sheet.Files {
+		for _, t := range f.Tracks {
+			switch t.DataType {
+			case DataTypeMode1_2048, DataTypeMode1_2352:
+				if session == "CD_DA" {
+					session = "CD_ROM"
+				}
+			case DataTypeMode2_2336, DataTypeMode2_2352, DataTypeCdi_2336, DataTypeCdi_2352:
+				session = "CD_ROM_XA"
+			}
+		}
+	}
+	fmt.Fprintf(&buf, "%s\n", session)
+	if sheet.Catalog != "" {
+		fmt.Fprintf(&buf, "CATALOG %s\n", quote(sheet.Catalog))
+	}
+	writeTOCText(&buf, sheet.Title, sheet.Performer, sheet.Songwriter, true)
+
+	for _, f := range sheet.Files {
+		// Sizes of the last file track is not needed.
+		var extents []Extent
+		if isRawFile(f) {
+			var err error
+			if extents, err = f.Extents(1 << 62); err != nil {
+				return err
+			}
+		}
+
+		for ti, t := range f.Tracks {
+			mode := ""
+			for k, v := range tocModes {
+				if v == t.DataType {
+					mode = k
+				}
+			}
+			if mode == "" {
+				return fmt.Errorf("track %d: %s datatype is not supported by TOC", t.Number, t.DataType)
+			}
+			if isContinued(t) {
+				return fmt.Errorf("track %d: gaps appended layout is not supported by TOC", t.Number)
+			}
+
+			fmt.Fprintf(&buf, "\n// Track %d\nTRACK %s\n", t.Number, mode)
+			has := func(flag TrackFlag) bool {
+				for _, f := range t.Flags {
+					if f == flag {
+						return true
+					}
+				}
+				return false
+			}
+			if has(TrackFlagDcp) {
+				buf.WriteString("COPY\n")
+			} else {
+				buf.WriteString("NO COPY\n")
+			}
+			if t.DataType.IsAudio() {
+				if has(TrackFlagPre) {
+					buf.WriteString("PRE_EMPHASIS\n")
+				} else {
+					buf.WriteString("NO PRE_EMPHASIS\n")
+				}
+				if has(TrackFlag4ch) {
+					buf.WriteString("FOUR_CHANNEL_AUDIO\n")
+				} else {
+					buf.WriteString("TWO_CHANNEL_AUDIO\n")
+				}
+			}
+			if t.Isrc != "" {
+				fmt.Fprintf(&buf, "ISRC %s\n", quote(t.Isrc))
+			}
+			writeTOCText(&buf, t.Title, t.Performer, t.Songwriter, false)
+			if t.Pregap.TotalFrames() != 0 {
+				fmt.Fprintf(&buf, "PREGAP %s\n", t.Pregap)
+			}
+			if len(t.Indexes) == 0 {
+				return fmt.Errorf("track %d has no indexes", t.Number)
+			}
+
+			first := t.Indexes[0].Time.TotalFrames()
+			length := ""
+			if ti+1 < len(f.Tracks) && len(f.Tracks[ti+1].Indexes) > 0 {
+				length = " " + TimeFromFrames(f.Tracks[ti+1].Indexes[0].Time.TotalFrames()-first).String()
+			}
+			switch {
+			case !t.DataType.IsAudio():
+				fmt.Fprintf(&buf, "DATAFILE %s #%d%s\n", quote(f.Name), extents[ti].Offset, length)
+			case extents != nil:
+				// cdrdao reads raw audio as big-endian.
+				swap := ""
+				if f.Type == FileTypeBinary {
+					swap = " SWAP"
+				}
+				fmt.Fprintf(&buf, "FILE %s%s #%d 0%s\n", quote(f.Name), swap, extents[ti].Offset, length)
+			default:
+				fmt.Fprintf(&buf, "FILE %s %s%s\n", quote(f.Name), TimeFromFrames(first), length)
+			}
+
+			start := t.StartTime().TotalFrames()
+			if start != first {
+				fmt.Fprintf(&buf, "START %s\n", TimeFromFrames(start-first))
+			}
+			for _, idx := range t.Indexes {
+				if idx.Number > 1 {
+					fmt.Fprintf(&buf, "INDEX %s\n", TimeFromFrames(idx.Time.TotalFrames()-start))
+				}
+			}
+			if t.Postgap.TotalFrames() != 0 {
+				fmt.Fprintf(&buf, "SILENCE %s\n", t.Postgap)
+			}
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeTOCText writes CD_TEXT block if any of the values is not empty.
+func writeTOCText(buf *bytes.Buffer, title, performer, songwriter string, disc bool) {
+	if title == "" && performer == "" && songwriter == "" {
+		return
+	}
+	buf.WriteString("CD_TEXT {\n")
+	if disc {
+		buf.WriteString("  LANGUAGE_MAP {\n    0 : EN\n  }\n")
+	}
+	buf.WriteString("  LANGUAGE 0 {\n")
+	for _, item := range [][2]string{{"TITLE", title}, {"PERFORMER", performer}, {"SONGWRITER", songwriter}} {
+		if item[1] != "" {
+			fmt.Fprintf(buf, "    %s %s\n", item[0], quote(item[1]))
+		}
+	}
+	buf.WriteString("  }\n}\n")
+}
@@ -0,0 +1,205 @@
+package cue
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const tocAudio = `CD_DA
+CATALOG "4006381333931"
+
+// Disc CD-TEXT.
+CD_TEXT {
+  LANGUAGE_MAP {
+    0 : EN
+  }
+  LANGUAGE 0 {
+    TITLE "Album \"Title\""
+    PERFORMER "Artist"
+    SIZE_INFO { 1, 2, 3 }
+  }
+}
+
+TRACK AUDIO
+NO COPY
+PRE_EMPHASIS
+TWO_CHANNEL_AUDIO
+ISRC "USRC17607839"
+CD_TEXT {
+  LANGUAGE 0 {
+    TITLE "First"
+    SONGWRITER "Writer"
+  }
+}
+PREGAP 0:2:0
+FILE "album.wav" 0 3:0:0
+
+TRACK AUDIO
+COPY
+FOUR_CHANNEL_AUDIO
+FILE "album.wav" 3:0:0 2:0:0
+START 0:1:30
+INDEX 0:30:0
+
+TRACK AUDIO
+FILE "bonus.bin" 0
+`
+
+func TestParseTOC(t *testing.T) {
+	sheet, err := ParseTOC(strings.NewReader(tocAudio))
+	if err != nil {
+		t.Fatalf("Failed to parse TOC. %s", err.Error())
+	}
+
+	if sheet.Catalog != "4006381333931" || sheet.Title != `Album "Title"` || sheet.Performer != "Artist" {
+		t.Fatalf("unexpected disc info %+v", sheet)
+	}
+	if len(sheet.Files) != 2 || len(sheet.Files[0].Tracks) != 2 {
+		t.Fatalf("expected 2 files with 2 and 1 tracks but %d received", len(sheet.Files))
+	}
+	if f := sheet.Files[1]; f.Name != "bonus.bin" || f.Type != FileTypeMotorola {
+		t.Fatalf("expected MOTOROLA bonus.bin file but %s %s received", f.Type, f.Name)
+	}
+
+	t1 := sheet.Files[0].Tracks[0]
+	if t1.Title != "First" || t1.Songwriter != "Writer" || t1.Isrc != "USRC17607839" {
+		t.Fatalf("unexpected track 1 info %+v", t1)
+	}
+	if len(t1.Flags) != 1 || t1.Flags[0] != TrackFlagPre {
+		t.Fatalf("expected PRE flag but %v received", t1.Flags)
+	}
+	if t1.Pregap != (Time{0, 2, 0}) {
+		t.Fatalf("expected 00:02:00 pregap but %s received", t1.Pregap)
+	}
+
+	t2 := sheet.Files[0].Tracks[1]
+	if len(t2.Flags) != 2 || t2.Flags[0] != TrackFlagDcp || t2.Flags[1] != TrackFlag4ch {
+		t.Fatalf("expected DCP 4CH flags but %v received", t2.Flags)
+	}
+	expected := []Index{
+		{Number: 0, Time: Time{3, 0, 0}},
+		{Number: 1, Time: Time{3, 1, 30}},
+		{Number: 2, Time: Time{3, 31, 30}},
+	}
+	if len(t2.Indexes) != len(expected) {
+		t.Fatalf("expected %d indexes but %d received", len(expected), len(t2.Indexes))
+	}
+	for i, idx := range expected {
+		if t2.Indexes[i] != idx {
+			t.Fatalf("expected index %v but %v received", idx, t2.Indexes[i])
+		}
+	}
+}
+
+func TestParseTOCDataFile(t *testing.T) {
+	sheet, err := ParseTOC(strings.NewReader(`CD_ROM
+TRACK MODE1_RAW
+DATAFILE "image.bin" #0 1:0:0
+TRACK AUDIO
+FILE "image.bin" #10584000 0
+START 0:2:0
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse TOC. %s", err.Error())
+	}
+	if len(sheet.Files) != 1 || sheet.Files[0].Type != FileTypeBinary {
+		t.Fatalf("expected single BINARY file")
+	}
+	t2 := sheet.Files[0].Tracks[1]
+	if t2.Indexes[0].Time != (Time{1, 0, 0}) || t2.Indexes[1].Time != (Time{1, 2, 0}) {
+		t.Fatalf("unexpected track 2 indexes %v", t2.Indexes)
+	}
+
+	for _, toc := range []string{
+		"TRACK MODE2_FORM1\nDATAFILE \"a.bin\"\n",
+		"TRACK AUDIO\nPREGAP 0:2:0\n",
+		"TRACK AUDIO\nFILE \"a.wav\" 0\nFILE \"b.wav\" 0\n",
+		"CATALOG \"123\n",
+	} {
+		if _, err := ParseTOC(strings.NewReader(toc)); err == nil {
+			t.Fatalf("expected error for %q", toc)
+		}
+	}
+}
+
+func TestWriteTOC(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(`CATALOG 4006381333931
+PERFORMER "Artist"
+TITLE "Album"
+FILE "image.bin" BINARY
+  TRACK 01 MODE1/2352
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Song"
+    FLAGS DCP PRE
+    ISRC USRC17607839
+    INDEX 00 01:00:00
+    INDEX 01 01:02:00
+    INDEX 02 01:10:00
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTOC(&buf, sheet); err != nil {
+		t.Fatalf("Failed to write TOC. %s", err.Error())
+	}
+	for _, s := range []string{
+		"CD_ROM\n",
+		"TRACK MODE1_RAW\nNO COPY\nDATAFILE \"image.bin\" #0 01:00:00\n",
+		"TRACK AUDIO\nCOPY\nPRE_EMPHASIS\nTWO_CHANNEL_AUDIO\nISRC \"USRC17607839\"\n",
+		"FILE \"image.bin\" SWAP #10584000 0\nSTART 00:02:00\nINDEX 00:08:00\n",
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Fatalf("expected %q in TOC but received\n%s", s, buf.String())
+		}
+	}
+
+	parsed, err := ParseTOC(&buf)
+	if err != nil {
+		t.Fatalf("Failed to parse written TOC. %s", err.Error())
+	}
+	var a, b bytes.Buffer
+	Write(&a, sheet)
+	Write(&b, parsed)
+	if a.String() != b.String() {
+		t.Fatalf("expected\n%s\nbut\n%s\nreceived", a.String(), b.String())
+	}
+
+	sheet.Files[0].Tracks[0].DataType = DataTypeCdg
+	if err := WriteTOC(&buf, sheet); err == nil {
+		t.Fatalf("expected error for CDG track")
+	}
+}
+
+func TestTOCSwap(t *testing.T) {
+	for _, test := range []struct {
+		fileType FileType
+		file     string
+	}{
+		{FileTypeBinary, `FILE "audio.bin" SWAP #0 0`},
+		{FileTypeMotorola, `FILE "audio.bin" #0 0`},
+	} {
+		sheet, err := Parse(strings.NewReader("FILE audio.bin " + test.fileType.String() +
+			"\n  TRACK 01 AUDIO\n    INDEX 01 00:00:00\n"))
+		if err != nil {
+			t.Fatalf("Failed to parse sheet. %s", err.Error())
+		}
+		var buf bytes.Buffer
+		if err := WriteTOC(&buf, sheet); err != nil {
+			t.Fatalf("Failed to write TOC. %s", err.Error())
+		}
+		if !strings.Contains(buf.String(), test.file+"\n") {
+			t.Fatalf("expected %q in TOC but received\n%s", test.file, buf.String())
+		}
+		parsed, err := ParseTOC(&buf)
+		if err != nil {
+			t.Fatalf("Failed to parse written TOC. %s", err.Error())
+		}
+		if f := parsed.Files[0]; f.Type != test.fileType {
+			t.Fatalf("expected %s file but %s received", test.fileType, f.Type)
+		}
+	}
+}
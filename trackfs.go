@@ -0,0 +1,432 @@
+package cue
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TrackFS is the read-only file system which serves the sheet tracks as
+// separate WAVE files ("NN - Title.wav") cut from the files referenced by
+// the sheet. WAVE files and audio tracks of BINARY/MOTOROLA files are
+// supported, tracks of the other files are not served.
+// Every track starts with its INDEX 01 and ends with INDEX 01 of the next
+// track (or the end of the file), so pregaps are appended to the previous track.
+type TrackFS struct {
+	src     fs.FS
+	entries []*trackEntry
+}
+
+// trackEntry describes virtual track file.
+type trackEntry struct {
+	name    string
+	track   *Track
+	source  string
+	format  waveFormat
+	header  []byte
+	offset  int64
+	size    int64
+	swap    bool
+	modTime time.Time
+}
+
+// NewTrackFS returns file system of the sheet tracks. src contains files
+// referenced by the sheet, which should implement io.ReaderAt.
+func NewTrackFS(sheet *Sheet, src fs.FS) (*TrackFS, error) {
+	tfs := &TrackFS{src: src}
+	for _, f := range sheet.Files {
+		if f.Type != FileTypeWave && !isRawFile(f) {
+			continue
+		}
+
+		file, r, info, err := openReaderAt(src, f.Name)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := fileEntries(f, r, info.Size())
+		file.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "file %s", f.Name)
+		}
+		for _, e := range entries {
+			e.source = f.Name
+			e.modTime = info.ModTime()
+			e.header = e.format.header(e.size)
+		}
+		tfs.entries = append(tfs.entries, entries...)
+	}
+
+	sort.Slice(tfs.entries, func(i, j int) bool {
+		return tfs.entries[i].name < tfs.entries[j].name
+	})
+
+	return tfs, nil
+}
+
+// fileEntries returns virtual files of the sheet file tracks.
+func fileEntries(f *File, r io.ReaderAt, size int64) ([]*trackEntry, error) {
+	var entries []*trackEntry
+
+	if f.Type == FileTypeWave {
+		format, err := readWaveFormat(r, size)
+		if err != nil {
+			return nil, err
+		}
+		for i, t := range f.Tracks {
+			if !hasStartIndex(t) {
+				continue
+			}
+			start := format.frameOffset(t.StartTime().TotalFrames())
+			end := format.dataSize
+			if i+1 < len(f.Tracks) && hasStartIndex(f.Tracks[i+1]) {
+				end = format.frameOffset(f.Tracks[i+1].StartTime().TotalFrames())
+			}
+			if start > end || end > format.dataSize {
+				return nil, fmt.Errorf("track %d is out of the audio data", t.Number)
+			}
+			entries = append(entries, &trackEntry{
+				name:   trackFileName(t),
+				track:  t,
+				format: format,
+				offset: format.dataOffset + start,
+				size:   end - start,
+			})
+		}
+		return entries, nil
+	}
+
+	extents, err := f.Extents(size)
+	if err != nil {
+		return nil, err
+	}
+	for i, e := range extents {
+		if e.Track.DataType != DataTypeAudio || !hasStartIndex(e.Track) {
+			continue
+		}
+		start := e.Offset + cdFormat.frameOffset(e.Track.StartTime().TotalFrames()-e.Start)
+		end := e.Offset + e.Length
+		if i+1 < len(extents) && extents[i+1].Track.DataType == DataTypeAudio {
+			next := extents[i+1]
+			end = next.Offset + cdFormat.frameOffset(next.Track.StartTime().TotalFrames()-next.Start)
+		}
+		entries = append(entries, &trackEntry{
+			name:   trackFileName(e.Track),
+			track:  e.Track,
+			format: cdFormat,
+			offset: start,
+			size:   end - start,
+			swap:   f.Type == FileTypeMotorola,
+		})
+	}
+
+	return entries, nil
+}
+
+// trackFileName returns "NN - Title.wav" file name of the track.
+func trackFileName(t *Track) string {
+	if t.Title == "" {
+		return fmt.Sprintf("%02d.wav", t.Number)
+	}
+	title := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, t.Title)
+	return fmt.Sprintf("%02d - %s.wav", t.Number, title)
+}
+
+// openReaderAt opens the file which implements io.ReaderAt.
+func openReaderAt(fsys fs.FS, name string) (fs.File, io.ReaderAt, fs.FileInfo, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	r, ok := file.(io.ReaderAt)
+	if !ok {
+		file.Close()
+		return nil, nil, nil, fmt.Errorf("file %s doesn't implement io.ReaderAt", name)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, nil, err
+	}
+	return file, r, info, nil
+}
+
+// ReadDurations sets durations of the sheet WAVE and raw (BINARY/MOTOROLA)
+// files from the files in fsys and updates tracks positions. Durations of
+// the other files are left as is.
+// Files which can't be opened or read keep their durations, the other
+// files are still read and the first error is returned. Errors of opening
+// the files are returned as is, e.g. *fs.PathError.
+func (s *Sheet) ReadDurations(fsys fs.FS) error {
+	var firstErr error
+	for _, f := range s.Files {
+		if f.Type != FileTypeWave && !isRawFile(f) {
+			continue
+		}
+		if err := readDuration(f, fsys); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	setPositions(s)
+
+	return firstErr
+}
+
+// readDuration sets duration of the WAVE or raw file from the file in fsys.
+func readDuration(f *File, fsys fs.FS) error {
+	file, r, info, err := openReaderAt(fsys, f.Name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if f.Type == FileTypeWave {
+		format, err := readWaveFormat(r, info.Size())
+		if err != nil {
+			return errors.Wrapf(err, "file %s", f.Name)
+		}
+		f.Duration = float64(format.dataSize/int64(format.blockAlign)) / float64(format.sampleRate)
+		return nil
+	}
+
+	extents, err := f.Extents(info.Size())
+	if err != nil {
+		return errors.Wrapf(err, "file %s", f.Name)
+	}
+	if l := len(extents); l > 0 {
+		f.Duration = TimeFromFrames(extents[l-1].Start + extents[l-1].Sectors).Seconds()
+	}
+	return nil
+}
+
+// Open implements fs.FS.
+func (tfs *TrackFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &trackDir{tfs: tfs}, nil
+	}
+
+	for _, e := range tfs.entries {
+		if e.name == name {
+			file, r, _, err := openReaderAt(tfs.src, e.source)
+			if err != nil {
+				return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+			}
+			return &TrackFile{entry: e, file: file, r: r}, nil
+		}
+	}
+
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (tfs *TrackFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name != "." {
+		if !fs.ValidPath(name) {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+		}
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]fs.DirEntry, len(tfs.entries))
+	for i, e := range tfs.entries {
+		entries[i] = fs.FileInfoToDirEntry(e)
+	}
+	return entries, nil
+}
+
+// Track returns track served by the file with the given name.
+func (tfs *TrackFS) Track(name string) *Track {
+	for _, e := range tfs.entries {
+		if e.name == name {
+			return e.track
+		}
+	}
+	return nil
+}
+
+// Name implements fs.FileInfo.
+func (e *trackEntry) Name() string { return e.name }
+
+// Size implements fs.FileInfo.
+func (e *trackEntry) Size() int64 { return int64(len(e.header)) + e.size }
+
+// Mode implements fs.FileInfo.
+func (e *trackEntry) Mode() fs.FileMode { return 0444 }
+
+// ModTime implements fs.FileInfo.
+func (e *trackEntry) ModTime() time.Time { return e.modTime }
+
+// IsDir implements fs.FileInfo.
+func (e *trackEntry) IsDir() bool { return false }
+
+// Sys implements fs.FileInfo.
+func (e *trackEntry) Sys() interface{} { return e.track }
+
+// TrackFile is the virtual WAVE file of the track served by TrackFS.
+// It implements io.ReaderAt and io.Seeker.
+type TrackFile struct {
+	entry  *trackEntry
+	file   fs.File
+	r      io.ReaderAt
+	offset int64
+}
+
+// Stat implements fs.File.
+func (f *TrackFile) Stat() (fs.FileInfo, error) {
+	return f.entry, nil
+}
+
+// Read implements io.Reader.
+func (f *TrackFile) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.offset)
+	f.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// ReadAt implements io.ReaderAt.
+func (f *TrackFile) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.entry.name, Err: fs.ErrInvalid}
+	}
+	size := f.entry.Size()
+	if off >= size {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > size-off {
+		p = p[:size-off]
+		err = io.EOF
+	}
+
+	// Header part.
+	hLen := int64(len(f.entry.header))
+	if off < hLen {
+		n = copy(p, f.entry.header[off:])
+		off += int64(n)
+	}
+	if n == len(p) {
+		return n, err
+	}
+
+	// Audio data part.
+	m, e := f.readData(p[n:], off-hLen)
+	if e != nil {
+		err = e
+	}
+	return n + m, err
+}
+
+// readData reads audio data at the offset, swapping bytes for MOTOROLA files.
+func (f *TrackFile) readData(p []byte, off int64) (int, error) {
+	e := f.entry
+	if !e.swap {
+		return f.r.ReadAt(p, e.offset+off)
+	}
+
+	// Read whole 16-bit samples to swap their bytes.
+	start := off &^ 1
+	buf := make([]byte, (off+int64(len(p))+1)&^1-start)
+	n, err := f.r.ReadAt(buf, e.offset+start)
+	if n < len(buf) {
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	for i := 0; i+1 < len(buf); i += 2 {
+		buf[i], buf[i+1] = buf[i+1], buf[i]
+	}
+	return copy(p, buf[off-start:]), nil
+}
+
+// Seek implements io.Seeker.
+func (f *TrackFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.entry.Size()
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.entry.name, Err: fs.ErrInvalid}
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.entry.name, Err: fs.ErrInvalid}
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// Close implements fs.File.
+func (f *TrackFile) Close() error {
+	return f.file.Close()
+}
+
+// trackDir is the root directory of TrackFS.
+type trackDir struct {
+	tfs    *TrackFS
+	offset int
+}
+
+// Stat implements fs.File.
+func (d *trackDir) Stat() (fs.FileInfo, error) {
+	return d, nil
+}
+
+// Read implements fs.File.
+func (d *trackDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: ".", Err: errors.New("is a directory")}
+}
+
+// Close implements fs.File.
+func (d *trackDir) Close() error {
+	return nil
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *trackDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	entries, _ := d.tfs.ReadDir(".")
+	entries = entries[d.offset:]
+	if count > 0 {
+		if len(entries) == 0 {
+			return nil, io.EOF
+		}
+		if count < len(entries) {
+			entries = entries[:count]
+		}
+	}
+	d.offset += len(entries)
+	return entries, nil
+}
+
+// Name implements fs.FileInfo.
+func (d *trackDir) Name() string { return "." }
+
+// Size implements fs.FileInfo.
+func (d *trackDir) Size() int64 { return 0 }
+
+// Mode implements fs.FileInfo.
+func (d *trackDir) Mode() fs.FileMode { return fs.ModeDir | 0555 }
+
+// ModTime implements fs.FileInfo.
+func (d *trackDir) ModTime() time.Time { return time.Time{} }
+
+// IsDir implements fs.FileInfo.
+func (d *trackDir) IsDir() bool { return true }
+
+// Sys implements fs.FileInfo.
+func (d *trackDir) Sys() interface{} { return nil }
@@ -0,0 +1,177 @@
+package cue
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// sampleBytes returns n bytes of audio data with values depending on position.
+func sampleBytes(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i / 7)
+	}
+	return data
+}
+
+func TestTrackFS(t *testing.T) {
+	const frame = 2352
+	sheet, err := Parse(strings.NewReader(`FILE "image.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "One/Two"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Three"
+    INDEX 00 00:00:02
+    INDEX 01 00:00:03
+FILE "image.bin" MOTOROLA
+  TRACK 03 MODE1/2352
+    INDEX 01 00:00:00
+  TRACK 04 AUDIO
+    INDEX 00 00:00:01
+    INDEX 01 00:00:02
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	audio := sampleBytes(5 * frame)
+	wave := append(cdFormat.header(int64(len(audio))), audio...)
+	bin := append(make([]byte, frame), sampleBytes(4*frame)...)
+
+	tfs, err := NewTrackFS(sheet, fstest.MapFS{
+		"image.wav": {Data: wave},
+		"image.bin": {Data: bin},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create file system. %s", err.Error())
+	}
+
+	if err := fstest.TestFS(tfs, "01 - One_Two.wav", "02 - Three.wav", "04.wav"); err != nil {
+		t.Fatal(err)
+	}
+
+	swapped := append([]byte(nil), bin[2*frame:]...)
+	for i := 0; i < len(swapped); i += 2 {
+		swapped[i], swapped[i+1] = swapped[i+1], swapped[i]
+	}
+	var tests = []struct {
+		name string
+		data []byte
+	}{
+		{"01 - One_Two.wav", audio[:3*frame]},
+		{"02 - Three.wav", audio[3*frame:]},
+		{"04.wav", swapped},
+	}
+	for _, tt := range tests {
+		data, err := fs.ReadFile(tfs, tt.name)
+		if err != nil {
+			t.Fatalf("Failed to read %s. %s", tt.name, err.Error())
+		}
+		expected := append(cdFormat.header(int64(len(tt.data))), tt.data...)
+		if !bytes.Equal(data, expected) {
+			t.Fatalf("%s: unexpected file data", tt.name)
+		}
+
+		f, _ := tfs.Open(tt.name)
+		tf := f.(*TrackFile)
+		buf := make([]byte, 5)
+		if _, err := tf.Seek(waveHeaderSize+3, io.SeekStart); err != nil {
+			t.Fatalf("Failed to seek %s. %s", tt.name, err.Error())
+		}
+		if _, err := io.ReadFull(tf, buf); err != nil || !bytes.Equal(buf, tt.data[3:8]) {
+			t.Fatalf("%s: unexpected data after seek", tt.name)
+		}
+		tf.Close()
+	}
+
+	if tr := tfs.Track("04.wav"); tr == nil || tr.Number != 4 {
+		t.Fatalf("unexpected track of 04.wav")
+	}
+}
+
+func TestTrackFSExtensible(t *testing.T) {
+	sheet, err := Parse(strings.NewReader("FILE \"image.wav\" WAVE\n  TRACK 01 AUDIO\n    INDEX 01 00:00:00\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	// WAVE_FORMAT_EXTENSIBLE fmt chunk with the channel mask and KSDATAFORMAT_SUBTYPE_PCM.
+	format := cdFormat
+	format.fmtChunk = append(cdFormat.header(0)[20:36], make([]byte, 24)...)
+	binary.LittleEndian.PutUint16(format.fmtChunk, waveFormatExtensible)
+	binary.LittleEndian.PutUint16(format.fmtChunk[16:], 22)
+	binary.LittleEndian.PutUint16(format.fmtChunk[18:], 16)
+	binary.LittleEndian.PutUint32(format.fmtChunk[20:], 3)
+	copy(format.fmtChunk[24:], "\x01\x00\x00\x00\x00\x00\x10\x00\x80\x00\x00\xaa\x00\x38\x9b\x71")
+	audio := sampleBytes(2352)
+	wave := append(format.header(int64(len(audio))), audio...)
+
+	tfs, err := NewTrackFS(sheet, fstest.MapFS{"image.wav": {Data: wave}})
+	if err != nil {
+		t.Fatalf("Failed to create file system. %s", err.Error())
+	}
+	data, err := fs.ReadFile(tfs, "01.wav")
+	if err != nil {
+		t.Fatalf("Failed to read track. %s", err.Error())
+	}
+	if !bytes.Equal(data, wave) {
+		t.Fatalf("unexpected track data, source WAVE expected")
+	}
+}
+
+func TestReadDurations(t *testing.T) {
+	const frame = 2352
+	sheet, err := Parse(strings.NewReader(`FILE "one.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:00
+FILE "two.bin" BINARY
+  TRACK 02 AUDIO
+    INDEX 01 00:00:00
+  TRACK 03 MODE1/2048
+    INDEX 01 00:00:02
+FILE "three.mp3" MP3
+  TRACK 04 AUDIO
+    INDEX 01 00:00:00
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	audio := sampleBytes(75 * frame)
+	err = sheet.ReadDurations(fstest.MapFS{
+		"one.wav": {Data: append(cdFormat.header(int64(len(audio))), audio...)},
+		"two.bin": {Data: make([]byte, 2*frame+3*2048)},
+	})
+	if err != nil {
+		t.Fatalf("Failed to read durations. %s", err.Error())
+	}
+	if d := sheet.Files[0].Duration; d != 1 {
+		t.Fatalf("unexpected WAVE duration %f", d)
+	}
+	if d := sheet.Files[1].Duration; d != TimeFromFrames(5).Seconds() {
+		t.Fatalf("unexpected BINARY duration %f", d)
+	}
+	if sheet.Files[1].Tracks[1].EndPosition != sheet.Files[1].Duration || sheet.Files[2].Duration != 0 {
+		t.Fatalf("unexpected positions %+v", sheet.Files[1].Tracks[1])
+	}
+
+	// Missing and broken files keep durations, the other files are read.
+	sheet.Files[0].Duration, sheet.Files[1].Duration = 0, 0
+	err = sheet.ReadDurations(fstest.MapFS{
+		"one.wav": {Data: []byte("RIFF")},
+		"two.bin": {Data: make([]byte, 2*frame+3*2048)},
+	})
+	if err == nil || sheet.Files[0].Duration != 0 || sheet.Files[1].Duration != TimeFromFrames(5).Seconds() {
+		t.Fatalf("unexpected result of the broken file %v %+v", err, sheet.Files)
+	}
+	if err := sheet.ReadDurations(fstest.MapFS{}); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected error of the missing file, got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package cue
+
+import "fmt"
+
+// Type of the audio file.
+type FileType int
+
+const (
+	// Intel binary file (least significant byte first)
+	FileTypeBinary FileType = iota
+	// Motorola binary file (most significant byte first)
+	FileTypeMotorola
+	// Audio AIFF file
+	FileTypeAiff
+	// Audio WAVE file
+	FileTypeWave
+	// Audio MP3 file
+	FileTypeMp3
+)
+
+// Track datatype.
+type TrackDataType int
+
+const (
+	// AUDIO – Audio/Music (2352)
+	DataTypeAudio TrackDataType = iota
+	// CDG – Karaoke CD+G (2448)
+	DataTypeCdg
+	// MODE1/2048 – CDROM Mode1 Data (cooked)
+	DataTypeMode1_2048
+	// MODE1/2352 – CDROM Mode1 Data (raw)
+	DataTypeMode1_2352
+	// MODE2/2336 – CDROM-XA Mode2 Data
+	DataTypeMode2_2336
+	// MODE2/2352 – CDROM-XA Mode2 Data
+	DataTypeMode2_2352
+	// CDI/2336 – CDI Mode2 Data
+	DataTypeCdi_2336
+	// CDI/2352 – CDI Mode2 Data
+	DataTypeCdi_2352
+)
+
+// Additional decode information about track.
+type TrackFlag int
+
+const (
+	// Digital copy permitted.
+	TrackFlagDcp TrackFlag = iota
+	// Four channel audio.
+	TrackFlag4ch
+	// Pre-emphasis enabled (audio tracks only).
+	TrackFlagPre
+	// Serial copy management system (not supported by all recorders).
+	TrackFlagScms
+)
+
+// fileTypeNames contains FILE command keywords indexed by FileType.
+var fileTypeNames = []string{"BINARY", "MOTOROLA", "AIFF", "WAVE", "MP3"}
+
+// dataTypeInfo describes TRACK command datatype.
+type dataTypeInfo struct {
+	name       string
+	sectorSize int
+	audio      bool
+}
+
+// dataTypes contains datatypes descriptions indexed by TrackDataType.
+var dataTypes = []dataTypeInfo{
+	{"AUDIO", 2352, true},
+	{"CDG", 2448, true},
+	{"MODE1/2048", 2048, false},
+	{"MODE1/2352", 2352, false},
+	{"MODE2/2336", 2336, false},
+	{"MODE2/2352", 2352, false},
+	{"CDI/2336", 2336, false},
+	{"CDI/2352", 2352, false},
+}
+
+// trackFlagNames contains FLAGS command keywords indexed by TrackFlag.
+var trackFlagNames = []string{"DCP", "4CH", "PRE", "SCMS"}
+
+// String returns FILE command keyword of the file type.
+func (t FileType) String() string {
+	if t < 0 || int(t) >= len(fileTypeNames) {
+		return fmt.Sprintf("FileType(%d)", int(t))
+	}
+	return fileTypeNames[t]
+}
+
+// ParseFileType returns file type by its FILE command keyword.
+func ParseFileType(s string) (FileType, error) {
+	for i, name := range fileTypeNames {
+		if name == s {
+			return FileType(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown file type: %s", s)
+}
+
+// String returns TRACK command keyword of the datatype.
+func (t TrackDataType) String() string {
+	if t < 0 || int(t) >= len(dataTypes) {
+		return fmt.Sprintf("TrackDataType(%d)", int(t))
+	}
+	return dataTypes[t].name
+}
+
+// SectorSize returns size of the datatype sector in bytes.
+func (t TrackDataType) SectorSize() int {
+	if t < 0 || int(t) >= len(dataTypes) {
+		return 0
+	}
+	return dataTypes[t].sectorSize
+}
+
+// IsAudio returns true for the audio datatypes.
+func (t TrackDataType) IsAudio() bool {
+	if t < 0 || int(t) >= len(dataTypes) {
+		return false
+	}
+	return dataTypes[t].audio
+}
+
+// ParseDataType returns track datatype by its TRACK command keyword.
+func ParseDataType(s string) (TrackDataType, error) {
+	for i, info := range dataTypes {
+		if info.name == s {
+			return TrackDataType(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown track datatype: %s", s)
+}
+
+// String returns FLAGS command keyword of the flag.
+func (f TrackFlag) String() string {
+	if f < 0 || int(f) >= len(trackFlagNames) {
+		return fmt.Sprintf("TrackFlag(%d)", int(f))
+	}
+	return trackFlagNames[f]
+}
+
+// ParseTrackFlag returns track flag by its FLAGS command keyword.
+func ParseTrackFlag(s string) (TrackFlag, error) {
+	for i, name := range trackFlagNames {
+		if name == s {
+			return TrackFlag(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown track flag: %s", s)
+}
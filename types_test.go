@@ -0,0 +1,59 @@
+package cue
+
+import "testing"
+
+func TestEnumValues(t *testing.T) {
+	if FileTypeBinary != 0 || DataTypeAudio != 0 || TrackFlagDcp != 0 {
+		t.Fatalf("enums should start from zero")
+	}
+
+	for i, name := range fileTypeNames {
+		ft, err := ParseFileType(name)
+		if err != nil || int(ft) != i || ft.String() != name {
+			t.Fatalf("file type %s parsed as %v (%v)", name, ft, err)
+		}
+	}
+	for i, info := range dataTypes {
+		dt, err := ParseDataType(info.name)
+		if err != nil || int(dt) != i || dt.String() != info.name {
+			t.Fatalf("datatype %s parsed as %v (%v)", info.name, dt, err)
+		}
+	}
+	for i, name := range trackFlagNames {
+		f, err := ParseTrackFlag(name)
+		if err != nil || int(f) != i || f.String() != name {
+			t.Fatalf("track flag %s parsed as %v (%v)", name, f, err)
+		}
+	}
+
+	if _, err := ParseDataType("MODE3/2352"); err == nil {
+		t.Fatalf("unknown datatype accepted")
+	}
+	if s := TrackFlag(10).String(); s != "TrackFlag(10)" {
+		t.Fatalf("unexpected unknown flag name %s", s)
+	}
+}
+
+func TestDataTypeInfo(t *testing.T) {
+	var tests = []struct {
+		dataType   TrackDataType
+		sectorSize int
+		audio      bool
+	}{
+		{DataTypeAudio, 2352, true},
+		{DataTypeCdg, 2448, true},
+		{DataTypeMode1_2048, 2048, false},
+		{DataTypeMode2_2336, 2336, false},
+		{DataTypeCdi_2352, 2352, false},
+		{TrackDataType(-1), 0, false},
+	}
+
+	for _, tt := range tests {
+		if s := tt.dataType.SectorSize(); s != tt.sectorSize {
+			t.Fatalf("%s: expected %d sector size but %d received", tt.dataType, tt.sectorSize, s)
+		}
+		if a := tt.dataType.IsAudio(); a != tt.audio {
+			t.Fatalf("%s: expected %t audio but %t received", tt.dataType, tt.audio, a)
+		}
+	}
+}
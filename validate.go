@@ -0,0 +1,244 @@
+package cue
+
+import "fmt"
+
+// Severity of the validation finding.
+type Severity int
+
+const (
+	// Sheet may be played but can't be burned as is.
+	SeverityWarning Severity = iota
+	// Sheet violates the profile.
+	SeverityError
+)
+
+// String returns severity name.
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Finding describes one problem found by Validate.
+type Finding struct {
+	// Severity of the problem.
+	Severity Severity
+	// Identifier of the violated rule, e.g. "track-length".
+	Rule string
+	// Track number, 0 for disc level findings.
+	Track int
+	// Human readable description.
+	Message string
+}
+
+// String returns finding in the "severity: track N: message (rule)" form.
+func (f Finding) String() string {
+	if f.Track == 0 {
+		return fmt.Sprintf("%s: %s (%s)", f.Severity, f.Message, f.Rule)
+	}
+	return fmt.Sprintf("%s: track %d: %s (%s)", f.Severity, f.Track, f.Message, f.Rule)
+}
+
+// Profile describes rules and limits checked by Validate.
+// Zero values disable the corresponding checks.
+type Profile struct {
+	// Profile name.
+	Name string
+	// Severity of the profile findings.
+	Severity Severity
+	// Maximum number of tracks.
+	MaxTracks int
+	// Minimum track length.
+	MinTrackLength Time
+	// Maximum total disc length.
+	MaxLength Time
+	// Required pregap length of the first track.
+	FirstPregap Time
+	// Minimum INDEX 01 time of the first track in raw (BINARY/MOTOROLA) images.
+	RawFirstIndex Time
+	// Required pregap length of the audio track following a data track.
+	DataAudioPregap Time
+	// All tracks should be audio tracks.
+	AudioOnly bool
+	// ISRC is allowed for audio tracks only.
+	AudioIsrcOnly bool
+	// Index times should increase strictly across all tracks.
+	IncreasingIndexes bool
+	// Maximum number of sessions.
+	MaxSessions int
+	// The first session should contain audio tracks only and the later
+	// sessions data tracks only (Enhanced CD).
+	AudioFirstSession bool
+}
+
+var (
+	// Red Book (CD-DA) audio disc.
+	ProfileRedBookAudio = Profile{
+		Name:              "redbook-audio",
+		Severity:          SeverityError,
+		MaxTracks:         99,
+		MinTrackLength:    Time{0, 4, 0},
+		MaxLength:         Time{79, 59, 74},
+		FirstPregap:       Time{0, 2, 0},
+		RawFirstIndex:     Time{0, 2, 0},
+		AudioOnly:         true,
+		AudioIsrcOnly:     true,
+		IncreasingIndexes: true,
+		MaxSessions:       1,
+	}
+	// Mixed-mode disc: data track followed by audio tracks.
+	ProfileMixedMode = Profile{
+		Name:              "mixed-mode",
+		Severity:          SeverityError,
+		MaxTracks:         99,
+		MinTrackLength:    Time{0, 4, 0},
+		MaxLength:         Time{79, 59, 74},
+		FirstPregap:       Time{0, 2, 0},
+		DataAudioPregap:   Time{0, 2, 0},
+		AudioIsrcOnly:     true,
+		IncreasingIndexes: true,
+		MaxSessions:       1,
+	}
+	// Enhanced CD (CD-Extra): audio session followed by data session.
+	ProfileEnhancedCD = Profile{
+		Name:              "enhanced-cd",
+		Severity:          SeverityError,
+		MaxTracks:         99,
+		MinTrackLength:    Time{0, 4, 0},
+		MaxLength:         Time{79, 59, 74},
+		FirstPregap:       Time{0, 2, 0},
+		AudioIsrcOnly:     true,
+		IncreasingIndexes: true,
+		AudioFirstSession: true,
+	}
+	// Sheet suitable for playback only.
+	ProfileLenientPlayback = Profile{
+		Name:              "lenient-playback",
+		Severity:          SeverityWarning,
+		MaxTracks:         99,
+		AudioIsrcOnly:     true,
+		IncreasingIndexes: true,
+	}
+)
+
+// Validate checks the sheet against the profile and returns found problems.
+// Files durations are required for the length checks of multi-file sheets
+// and the last track.
+func Validate(sheet *Sheet, profile Profile) (findings []Finding) {
+	report := func(track int, rule string, format string, args ...interface{}) {
+		findings = append(findings, Finding{
+			Severity: profile.Severity,
+			Rule:     rule,
+			Track:    track,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	if sheet.Catalog != "" {
+		if err := ValidateCatalog(sheet.Catalog); err != nil {
+			report(0, "catalog", "%v", err)
+		}
+	}
+
+	if profile.MaxTracks > 0 && sheet.TracksCount() > profile.MaxTracks {
+		report(0, "track-count", "%d tracks, at most %d allowed", sheet.TracksCount(), profile.MaxTracks)
+	}
+
+	if profile.MaxSessions > 0 && len(sheet.Sessions) > profile.MaxSessions {
+		report(0, "session-count", "%d sessions, at most %d allowed", len(sheet.Sessions), profile.MaxSessions)
+	}
+	if profile.AudioFirstSession && len(sheet.Sessions) < 2 {
+		report(0, "session-content", "data session is missing")
+	}
+	for _, s := range sheet.Sessions {
+		if len(sheet.SessionTracks(s.Number)) == 0 {
+			report(0, "session-tracks", "session %d has no tracks", s.Number)
+		}
+	}
+
+	// Multi-file sheet positions are meaningless without files durations.
+	known := true
+	for i, f := range sheet.Files {
+		if f.Duration == 0 && i < len(sheet.Files)-1 {
+			known = false
+			report(0, "file-duration", "duration of the file %s is unknown", f.Name)
+		}
+	}
+
+	spans := timeline(sheet)
+	last := -1
+	length := 0
+	for i, sp := range spans {
+		t := sp.track
+		isAudio := t.DataType.IsAudio()
+
+		if profile.AudioOnly && !isAudio {
+			report(t.Number, "audio-only", "data track is not allowed")
+		}
+		if profile.AudioIsrcOnly && !isAudio && t.Isrc != "" {
+			report(t.Number, "isrc-audio", "ISRC is allowed for audio tracks only")
+		}
+		session := sheet.TrackSession(t.Number)
+		if profile.AudioFirstSession && (session == 1) != isAudio {
+			if isAudio {
+				report(t.Number, "session-content", "audio track is not allowed in session %d", session)
+			} else {
+				report(t.Number, "session-content", "data track is not allowed in the first session")
+			}
+		}
+		// Sessions are separated by lead-out and lead-in areas.
+		sessionStart := i > 0 && session != sheet.TrackSession(spans[i-1].track.Number)
+		if sessionStart {
+			length += sheet.sessionGap(session)
+		}
+
+		pregap := t.Pregap.TotalFrames() + sp.start - sp.gap
+		if i == 0 {
+			if pregap < profile.FirstPregap.TotalFrames() {
+				report(t.Number, "first-pregap", "pregap %s is shorter than %s",
+					TimeFromFrames(pregap), profile.FirstPregap)
+			}
+			if isRawFile(sp.file) && t.StartTime().TotalFrames() < profile.RawFirstIndex.TotalFrames() {
+				report(t.Number, "first-index", "INDEX 01 at %s, should be at or after %s",
+					t.StartTime(), profile.RawFirstIndex)
+			}
+		} else if isAudio && !sessionStart && !spans[i-1].track.DataType.IsAudio() &&
+			pregap < profile.DataAudioPregap.TotalFrames() {
+			report(t.Number, "data-audio-pregap", "pregap %s after data track is shorter than %s",
+				TimeFromFrames(pregap), profile.DataAudioPregap)
+		}
+
+		if profile.IncreasingIndexes {
+			for j, pos := range sp.indexes {
+				if known && pos <= last {
+					report(t.Number, "index-order", "INDEX %02d doesn't follow the previous index",
+						t.Indexes[j].Number)
+				}
+				last = pos
+			}
+		}
+
+		length += t.Pregap.TotalFrames() + t.Postgap.TotalFrames()
+		if !known || sp.end < 0 {
+			continue
+		}
+		if l, min := sp.end-sp.start, profile.MinTrackLength.TotalFrames(); min > 0 && l < min {
+			report(t.Number, "track-length", "length %s is shorter than %s",
+				TimeFromFrames(l), profile.MinTrackLength)
+		}
+		if i == len(spans)-1 {
+			length += sp.end
+			if limit := profile.MaxLength.TotalFrames(); limit > 0 && length > limit {
+				report(0, "disc-length", "length %s exceeds %s", TimeFromFrames(length), profile.MaxLength)
+			}
+		}
+	}
+
+	return findings
+}
+
+// isRawFile returns true if the file is raw binary image.
+func isRawFile(f *File) bool {
+	return f.Type == FileTypeBinary || f.Type == FileTypeMotorola
+}
@@ -0,0 +1,79 @@
+package cue
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func findingRules(findings []Finding) string {
+	var rules []string
+	for _, f := range findings {
+		rules = append(rules, f.Rule)
+	}
+	sort.Strings(rules)
+	return strings.Join(rules, ",")
+}
+
+func TestValidate(t *testing.T) {
+	// Wrong catalog check digit is reported by Validate, not Parse.
+	const input = `CATALOG 1234567890123
+FILE "image.bin" BINARY
+  TRACK 01 MODE1/2352
+    ISRC USABC1234567
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    INDEX 00 10:00:00
+    INDEX 01 10:01:00
+  TRACK 03 AUDIO
+    INDEX 01 10:03:00
+  TRACK 04 AUDIO
+    INDEX 01 00:00:10
+`
+	sheet, err := Parse(strings.NewReader(input), 15*60)
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	var tests = []struct {
+		profile Profile
+		rules   string
+	}{
+		{ProfileRedBookAudio, "audio-only,catalog,first-index,first-pregap,index-order,isrc-audio,track-length,track-length"},
+		{ProfileMixedMode, "catalog,data-audio-pregap,first-pregap,index-order,isrc-audio,track-length,track-length"},
+		{ProfileLenientPlayback, "catalog,index-order,isrc-audio"},
+	}
+
+	for _, tt := range tests {
+		findings := Validate(sheet, tt.profile)
+		if rules := findingRules(findings); rules != tt.rules {
+			t.Fatalf("profile %s: expected '%s' findings but '%s' received", tt.profile.Name, tt.rules, rules)
+		}
+		for _, f := range findings {
+			if f.Severity != tt.profile.Severity {
+				t.Fatalf("profile %s: unexpected severity of '%s'", tt.profile.Name, f)
+			}
+		}
+	}
+}
+
+func TestValidatePackage(t *testing.T) {
+	file, err := os.Open("test.cue")
+	if err != nil {
+		t.Fatalf("Failed to open file. %s", err.Error())
+	}
+	defer file.Close()
+
+	sheet, err := Parse(file, 40*60)
+	if err != nil {
+		t.Fatalf("Failed to parse file. %s", err.Error())
+	}
+
+	if findings := Validate(sheet, ProfileLenientPlayback); len(findings) != 0 {
+		t.Fatalf("unexpected findings: %v", findings)
+	}
+	if rules := findingRules(Validate(sheet, ProfileRedBookAudio)); rules != "first-pregap" {
+		t.Fatalf("expected 'first-pregap' finding but '%s' received", rules)
+	}
+}
@@ -0,0 +1,197 @@
+package cue
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// MSF address of the disc LBA 0 (2 seconds lead-in).
+const msfOffset = 150
+
+var (
+	// syncPattern starts every raw data sector.
+	syncPattern = []byte{0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0}
+
+	// EDC (CRC-32 with 0xd8018001 polynomial) lookup table.
+	edcTable [256]uint32
+	// ECC Galois field lookup tables.
+	eccFTable [256]byte
+	eccBTable [256]byte
+)
+
+func init() {
+	for i := 0; i < 256; i++ {
+		j := byte(i << 1)
+		if i&0x80 != 0 {
+			j ^= 0x1d
+		}
+		eccFTable[i] = j
+		eccBTable[byte(i)^j] = byte(i)
+
+		edc := uint32(i)
+		for k := 0; k < 8; k++ {
+			if edc&1 != 0 {
+				edc = edc>>1 ^ 0xd8018001
+			} else {
+				edc >>= 1
+			}
+		}
+		edcTable[i] = edc
+	}
+}
+
+// BadSector describes damaged sector.
+type BadSector struct {
+	// Sector position in the file.
+	LBA int
+	// Found problems: "sync", "header", "mode", "edc" or "ecc".
+	Problems []string
+}
+
+// String returns the bad sector description.
+func (s BadSector) String() string {
+	return fmt.Sprintf("sector %d: %v", s.LBA, s.Problems)
+}
+
+// TrackReport describes raw sectors verification result of the track.
+type TrackReport struct {
+	// Verified track.
+	Track *Track
+	// Number of verified sectors.
+	Sectors int
+	// Damaged sectors.
+	Bad []BadSector
+}
+
+// VerifySectors checks raw data sectors (MODE1/2352, MODE2/2352 and CDI/2352
+// tracks) of the binary file f: sync pattern, header address, EDC and
+// optionally ECC parity. r and size describe the file data, start is the disc
+// LBA of the first file sector (0 for the single-file images).
+// Tracks of the other datatypes are not reported.
+func VerifySectors(r io.ReaderAt, size int64, f *File, start int, ecc bool) ([]TrackReport, error) {
+	extents, err := f.Extents(size)
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []TrackReport
+	for _, e := range extents {
+		dt := e.Track.DataType
+		if dt != DataTypeMode1_2352 && dt != DataTypeMode2_2352 && dt != DataTypeCdi_2352 {
+			continue
+		}
+
+		report := TrackReport{Track: e.Track, Sectors: e.Sectors}
+		sr := NewSectorReader(r, e)
+		for lba := e.Start; lba < e.Start+e.Sectors; lba++ {
+			sector, err := sr.ReadSector(lba)
+			if err != nil {
+				return nil, err
+			}
+			if problems := verifySector(sector, dt, start+lba, ecc); len(problems) > 0 {
+				report.Bad = append(report.Bad, BadSector{LBA: lba, Problems: problems})
+			}
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// verifySector returns problems of the raw sector with the given disc LBA.
+func verifySector(sector []byte, dataType TrackDataType, lba int, ecc bool) (problems []string) {
+	if !bytes.Equal(sector[:len(syncPattern)], syncPattern) {
+		problems = append(problems, "sync")
+	}
+	if !bytes.Equal(sector[12:15], msf(lba+msfOffset)) {
+		problems = append(problems, "header")
+	}
+
+	mode := sector[15]
+	if dataType == DataTypeMode1_2352 && mode != 1 || dataType != DataTypeMode1_2352 && mode != 2 {
+		return append(problems, "mode")
+	}
+
+	var edcData []byte
+	var edcStored []byte
+	checkECC := ecc
+	switch {
+	case mode == 1:
+		edcData, edcStored = sector[:2064], sector[2064:2068]
+	case sector[18]&submodeForm2 == 0:
+		edcData, edcStored = sector[16:2072], sector[2072:2076]
+	default:
+		// Form 2 sectors have no ECC and EDC is optional.
+		edcData, edcStored = sector[16:2348], sector[2348:2352]
+		checkECC = false
+		if binary.LittleEndian.Uint32(edcStored) == 0 {
+			edcStored = nil
+		}
+	}
+	if edcStored != nil && binary.LittleEndian.Uint32(edcStored) != edc(edcData) {
+		problems = append(problems, "edc")
+	}
+
+	if checkECC {
+		parity := eccParity(sector, mode == 2)
+		if !bytes.Equal(parity, sector[2076:]) {
+			problems = append(problems, "ecc")
+		}
+	}
+
+	return problems
+}
+
+// msf returns BCD encoded minutes, seconds and frames of the address.
+func msf(address int) []byte {
+	bcd := func(v int) byte {
+		return byte(v/10<<4 | v%10)
+	}
+	t := TimeFromFrames(address)
+	return []byte{bcd(t.Min), bcd(t.Sec), bcd(t.Frames)}
+}
+
+// edc calculates error detection code of the data.
+func edc(data []byte) (edc uint32) {
+	for _, b := range data {
+		edc = edc>>8 ^ edcTable[byte(edc)^b]
+	}
+	return
+}
+
+// eccParity calculates P and Q parity bytes of the raw sector.
+// Header is treated as zero for MODE2 sectors.
+func eccParity(sector []byte, zeroHeader bool) []byte {
+	buf := make([]byte, rawSectorSize)
+	copy(buf, sector[:2076])
+	if zeroHeader {
+		copy(buf[12:16], []byte{0, 0, 0, 0})
+	}
+	eccBlock(buf[12:], 86, 24, 2, 86, buf[2076:])
+	eccBlock(buf[12:], 52, 43, 86, 88, buf[2248:])
+	return buf[2076:]
+}
+
+// eccBlock calculates Reed-Solomon product code parity (P or Q) of the data.
+func eccBlock(src []byte, majorCount, minorCount, majorMult, minorInc int, dest []byte) {
+	size := majorCount * minorCount
+	for major := 0; major < majorCount; major++ {
+		index := (major>>1)*majorMult + (major & 1)
+		var a, b byte
+		for minor := 0; minor < minorCount; minor++ {
+			temp := src[index]
+			index += minorInc
+			if index >= size {
+				index -= size
+			}
+			a ^= temp
+			b ^= temp
+			a = eccFTable[a]
+		}
+		a = eccBTable[eccFTable[a]^b]
+		dest[major] = a
+		dest[major+majorCount] = a ^ b
+	}
+}
@@ -0,0 +1,90 @@
+package cue
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// encodeSector fills header address, EDC and ECC of the raw sector.
+func encodeSector(sector []byte, lba int) []byte {
+	copy(sector[12:15], msf(lba+msfOffset))
+	switch {
+	case sector[15] == 1:
+		binary.LittleEndian.PutUint32(sector[2064:], edc(sector[:2064]))
+		copy(sector[2076:], eccParity(sector, false))
+	case sector[18]&submodeForm2 == 0:
+		binary.LittleEndian.PutUint32(sector[2072:], edc(sector[16:2072]))
+		copy(sector[2076:], eccParity(sector, true))
+	default:
+		binary.LittleEndian.PutUint32(sector[2348:], edc(sector[16:2348]))
+	}
+	return sector
+}
+
+func TestMSF(t *testing.T) {
+	if m := msf(msfOffset + 75*61 + 12); !bytes.Equal(m, []byte{0x01, 0x03, 0x12}) {
+		t.Fatalf("unexpected MSF % x", m)
+	}
+}
+
+func TestVerifySectors(t *testing.T) {
+	const start = 1000
+	sheet, err := Parse(strings.NewReader(`FILE "image.bin" BINARY
+  TRACK 01 MODE1/2352
+    INDEX 01 00:00:00
+  TRACK 02 MODE2/2352
+    INDEX 01 00:00:03
+  TRACK 03 AUDIO
+    INDEX 01 00:00:06
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	var sectors [][]byte
+	for lba := 0; lba < 6; lba++ {
+		mode := byte(1)
+		if lba >= 3 {
+			mode = 2
+		}
+		sector := rawSector(mode, lba == 5, byte(lba*37))
+		sectors = append(sectors, encodeSector(sector, start+lba))
+	}
+	sectors = append(sectors, make([]byte, rawSectorSize))
+
+	// Damage sectors: user data, P parity, address and form 2 data.
+	sectors[2][100]++
+	sectors[3][2100]++
+	sectors[4][14]++
+	sectors[5][1000]++
+
+	image := bytes.Join(sectors, nil)
+	var tests = []struct {
+		ecc     bool
+		reports string
+	}{
+		{false, "1: [sector 2: [edc]], 2: [sector 4: [header] sector 5: [edc]]"},
+		{true, "1: [sector 2: [edc ecc]], 2: [sector 3: [ecc] sector 4: [header] sector 5: [edc]]"},
+	}
+
+	for _, tt := range tests {
+		reports, err := VerifySectors(bytes.NewReader(image), int64(len(image)), sheet.Files[0], start, tt.ecc)
+		if err != nil {
+			t.Fatalf("Failed to verify sectors. %s", err.Error())
+		}
+
+		var result []string
+		for _, r := range reports {
+			if r.Sectors != 3 {
+				t.Fatalf("track %d: expected 3 sectors but %d received", r.Track.Number, r.Sectors)
+			}
+			result = append(result, fmt.Sprintf("%d: %v", r.Track.Number, r.Bad))
+		}
+		if s := strings.Join(result, ", "); s != tt.reports {
+			t.Fatalf("ECC %t: expected '%s' reports but '%s' received", tt.ecc, tt.reports, s)
+		}
+	}
+}
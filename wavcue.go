@@ -0,0 +1,454 @@
+package cue
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// Size of the cue chunk point.
+	cuePointSize = 24
+	// Multiplier of the track number in the cue point identifier,
+	// index number is added to it.
+	cuePointTrack = 100
+	// Purpose of the ltxt region.
+	ltxtRegion = "rgn "
+)
+
+// cuePoint is the WAVE cue chunk point with its adtl label and region.
+type cuePoint struct {
+	id uint32
+	// Position in samples.
+	offset uint32
+	label  string
+	// Region length in samples and text.
+	length uint32
+	text   string
+}
+
+// writeRIFFChunk writes chunk header, data and pad byte.
+func writeRIFFChunk(buf *bytes.Buffer, id string, data []byte) {
+	buf.WriteString(id)
+	binary.Write(buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+	if len(data)&1 != 0 {
+		buf.WriteByte(0)
+	}
+}
+
+// encodeCuePoints returns cue and LIST/adtl chunks of the cue points.
+func encodeCuePoints(points []cuePoint) []byte {
+	var cue, adtl, out bytes.Buffer
+	binary.Write(&cue, binary.LittleEndian, uint32(len(points)))
+	adtl.WriteString("adtl")
+	for _, p := range points {
+		binary.Write(&cue, binary.LittleEndian, p.id)
+		binary.Write(&cue, binary.LittleEndian, p.offset)
+		cue.WriteString("data")
+		binary.Write(&cue, binary.LittleEndian, [2]uint32{0, 0})
+		binary.Write(&cue, binary.LittleEndian, p.offset)
+
+		if p.label != "" {
+			var labl bytes.Buffer
+			binary.Write(&labl, binary.LittleEndian, p.id)
+			labl.WriteString(p.label + "\x00")
+			writeRIFFChunk(&adtl, "labl", labl.Bytes())
+		}
+		if p.length != 0 {
+			var ltxt bytes.Buffer
+			binary.Write(&ltxt, binary.LittleEndian, p.id)
+			binary.Write(&ltxt, binary.LittleEndian, p.length)
+			ltxt.WriteString(ltxtRegion)
+			// Country, language, dialect and code page.
+			binary.Write(&ltxt, binary.LittleEndian, [4]uint16{})
+			if p.text != "" {
+				ltxt.WriteString(p.text + "\x00")
+			}
+			writeRIFFChunk(&adtl, "ltxt", ltxt.Bytes())
+		}
+	}
+
+	writeRIFFChunk(&out, "cue ", cue.Bytes())
+	writeRIFFChunk(&out, "LIST", adtl.Bytes())
+	return out.Bytes()
+}
+
+// cString returns text of the null-terminated string.
+func cString(data []byte) string {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		data = data[:i]
+	}
+	text, err := DecodeText(data)
+	if err != nil {
+		return string(data)
+	}
+	return text
+}
+
+// readCuePoints reads cue points with their labels and regions.
+func readCuePoints(r io.ReaderAt, chunks []riffChunk) ([]cuePoint, error) {
+	var points []cuePoint
+	byID := map[uint32]int{}
+	read := func(c riffChunk) ([]byte, error) {
+		data := make([]byte, c.size)
+		_, err := r.ReadAt(data, c.offset)
+		return data, errors.Wrapf(err, "failed to read %s chunk", strings.TrimSpace(c.id))
+	}
+
+	for _, c := range chunks {
+		if c.id != "cue " {
+			continue
+		}
+		data, err := read(c)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) < 4 {
+			return nil, errors.New("cue chunk is too short")
+		}
+		count := int(binary.LittleEndian.Uint32(data))
+		if count > (len(data)-4)/cuePointSize {
+			return nil, errors.New("cue chunk points are out of chunk")
+		}
+		for i := 0; i < count; i++ {
+			p := data[4+i*cuePointSize:]
+			byID[binary.LittleEndian.Uint32(p)] = len(points)
+			points = append(points, cuePoint{
+				id:     binary.LittleEndian.Uint32(p),
+				offset: binary.LittleEndian.Uint32(p[20:]),
+			})
+		}
+	}
+
+	for _, c := range chunks {
+		if c.id != "LIST" || c.size < 4 {
+			continue
+		}
+		data, err := read(c)
+		if err != nil {
+			return nil, err
+		}
+		if string(data[:4]) != "adtl" {
+			continue
+		}
+		for data = data[4:]; len(data) >= 12; {
+			id, size := string(data[:4]), int(binary.LittleEndian.Uint32(data[4:]))
+			if size > len(data)-8 {
+				return nil, errors.New("adtl sub-chunk is out of LIST chunk")
+			}
+			sub := data[8 : 8+size]
+			if next := 8 + size + size&1; next < len(data) {
+				data = data[next:]
+			} else {
+				data = nil
+			}
+			if size < 4 {
+				continue
+			}
+			i, ok := byID[binary.LittleEndian.Uint32(sub)]
+			if !ok {
+				continue
+			}
+			switch {
+			case id == "labl":
+				points[i].label = cString(sub[4:])
+			case id == "ltxt" && size >= 20:
+				points[i].length = binary.LittleEndian.Uint32(sub[4:])
+				points[i].text = cString(sub[20:])
+			}
+		}
+	}
+
+	sort.SliceStable(points, func(i, j int) bool { return points[i].offset < points[j].offset })
+	return points, nil
+}
+
+// sheetCuePoints returns cue points of the single-file sheet indexes.
+func sheetCuePoints(sheet *Sheet, format waveFormat) ([]cuePoint, error) {
+	if len(sheet.Files) != 1 {
+		return nil, fmt.Errorf("single file sheet expected, but %d files found", len(sheet.Files))
+	}
+
+	toSamples := func(t Time) uint32 {
+		return uint32(int64(t.TotalFrames()) * int64(format.sampleRate) / framesPerSecond)
+	}
+	totalSamples := uint32(format.dataSize / int64(format.blockAlign))
+
+	var points []cuePoint
+	tracks := sheet.Files[0].Tracks
+	for ti, t := range tracks {
+		if t.Number >= 1<<31/cuePointTrack {
+			return nil, fmt.Errorf("illegal track number %d", t.Number)
+		}
+		end := totalSamples
+		if ti+1 < len(tracks) {
+			end = toSamples(tracks[ti+1].StartTime())
+		}
+		for _, idx := range t.Indexes {
+			p := cuePoint{id: uint32(t.Number*cuePointTrack + idx.Number), offset: toSamples(idx.Time)}
+			if p.offset > totalSamples {
+				return nil, fmt.Errorf("track %d INDEX %02d is out of audio data", t.Number, idx.Number)
+			}
+			if idx.Number == 1 {
+				p.label = t.Title
+				if end > p.offset {
+					p.length, p.text = end-p.offset, t.Performer
+				}
+			} else {
+				p.label = fmt.Sprintf("INDEX %02d", idx.Number)
+			}
+			points = append(points, p)
+		}
+	}
+
+	return points, nil
+}
+
+// isCueChunk returns true for the cue and LIST/adtl chunks.
+func isCueChunk(r io.ReaderAt, c riffChunk) (bool, error) {
+	if c.id == "cue " {
+		return true, nil
+	}
+	if c.id != "LIST" || c.size < 4 {
+		return false, nil
+	}
+	listType := make([]byte, 4)
+	if _, err := r.ReadAt(listType, c.offset); err != nil {
+		return false, errors.Wrap(err, "failed to read LIST chunk")
+	}
+	return string(listType) == "adtl", nil
+}
+
+// WriteWaveCues copies WAVE file of the given size from r to w replacing its
+// cue and LIST/adtl chunks with the single-file sheet indexes. Cue points
+// identifiers are track number * 100 + index number, INDEX 01 points are
+// labeled with the tracks titles and have regions up to the next track.
+// Audio data and other chunks are copied as is.
+func WriteWaveCues(w io.Writer, r io.ReaderAt, size int64, sheet *Sheet) error {
+	format, err := readWaveFormat(r, size)
+	if err != nil {
+		return err
+	}
+	points, err := sheetCuePoints(sheet, format)
+	if err != nil {
+		return err
+	}
+	cues := encodeCuePoints(points)
+	chunks, err := readChunks(r, size)
+	if err != nil {
+		return err
+	}
+
+	// Kept chunks are written with their (possibly clamped) sizes.
+	var kept []riffChunk
+	riffSize := int64(4 + len(cues))
+	for _, c := range chunks {
+		if ok, err := isCueChunk(r, c); err != nil {
+			return err
+		} else if ok {
+			continue
+		}
+		kept = append(kept, c)
+		riffSize += 8 + c.size + c.size&1
+	}
+	if riffSize > 1<<32-1 {
+		return errors.New("WAVE file is too large")
+	}
+
+	var header bytes.Buffer
+	header.WriteString("RIFF")
+	binary.Write(&header, binary.LittleEndian, uint32(riffSize))
+	header.WriteString("WAVE")
+	if _, err := w.Write(header.Bytes()); err != nil {
+		return err
+	}
+	for _, c := range kept {
+		header.Reset()
+		header.WriteString(c.id)
+		binary.Write(&header, binary.LittleEndian, uint32(c.size))
+		if _, err := w.Write(header.Bytes()); err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, io.NewSectionReader(r, c.offset, c.size)); err != nil {
+			return err
+		}
+		if c.size&1 != 0 {
+			if _, err := w.Write([]byte{0}); err != nil {
+				return err
+			}
+		}
+	}
+	_, err = w.Write(cues)
+	return err
+}
+
+// UpdateWaveCues replaces cue and LIST/adtl chunks of the WAVE file in place
+// like WriteWaveCues, but without rewriting the audio data: old chunks at the
+// end of the file are truncated, others are turned into JUNK chunks, and the
+// new chunks are appended.
+func UpdateWaveCues(f *os.File, sheet *Sheet) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	format, err := readWaveFormat(f, size)
+	if err != nil {
+		return err
+	}
+	points, err := sheetCuePoints(sheet, format)
+	if err != nil {
+		return err
+	}
+	cues := encodeCuePoints(points)
+	chunks, err := readChunks(f, size)
+	if err != nil {
+		return err
+	}
+
+	end := int64(12)
+	var removed []riffChunk
+	for _, c := range chunks {
+		ok, err := isCueChunk(f, c)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			// Clamped chunk size is fixed, chunks after it are removed anyway.
+			if c.offset+c.size == size {
+				var buf [4]byte
+				binary.LittleEndian.PutUint32(buf[:], uint32(c.size))
+				if _, err := f.WriteAt(buf[:], c.offset-4); err != nil {
+					return err
+				}
+			}
+			end = c.offset + c.size + c.size&1
+			continue
+		}
+		removed = append(removed, c)
+	}
+	for _, c := range removed {
+		if c.offset < end {
+			if _, err := f.WriteAt([]byte("JUNK"), c.offset-8); err != nil {
+				return err
+			}
+		}
+	}
+
+	riffSize := end - 8 + int64(len(cues))
+	if riffSize > 1<<32-1 {
+		return errors.New("WAVE file is too large")
+	}
+	if end > size {
+		// Pad byte of the last chunk.
+		if _, err := f.WriteAt([]byte{0}, size); err != nil {
+			return err
+		}
+	}
+	if _, err := f.WriteAt(cues, end); err != nil {
+		return err
+	}
+	if err := f.Truncate(end + int64(len(cues))); err != nil {
+		return err
+	}
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], uint32(riffSize))
+	_, err = f.WriteAt(buf[:], 4)
+	return err
+}
+
+// ReadWaveCues builds the sheet with the single WAVE file from the cue and
+// LIST/adtl chunks of the WAVE file of the given size. Points written by
+// WriteWaveCues (identifiers are track number * 100 + index number) are
+// restored as tracks indexes, other points become tracks labeled by the
+// labl texts. The file type is always WAVE. Returns rounding errors of the
+// tracks INDEX 01 times.
+func ReadWaveCues(r io.ReaderAt, size int64, opts ImportOptions) (*Sheet, []time.Duration, error) {
+	format, err := readWaveFormat(r, size)
+	if err != nil {
+		return nil, nil, err
+	}
+	chunks, err := readChunks(r, size)
+	if err != nil {
+		return nil, nil, err
+	}
+	points, err := readCuePoints(r, chunks)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(points) == 0 {
+		return nil, nil, errors.New("WAVE file has no cue points")
+	}
+
+	toDuration := func(samples uint32) time.Duration {
+		return time.Duration(int64(samples) * int64(time.Second) / int64(format.sampleRate))
+	}
+	totalSamples := uint32(format.dataSize / int64(format.blockAlign))
+	opts.FileType = FileTypeWave
+
+	if !isTrackCuePoints(points) {
+		chapters := make([]importedChapter, len(points))
+		for i, p := range points {
+			chapters[i] = importedChapter{start: toDuration(p.offset), end: toDuration(totalSamples), title: p.label}
+		}
+		return sheetFromChapters(chapters, opts)
+	}
+
+	sheet := new(Sheet)
+	file := &File{Name: opts.File, Type: opts.FileType, Duration: toDuration(totalSamples).Seconds()}
+	sheet.Files = []*File{file}
+	var roundingErrors []time.Duration
+	var track *Track
+	for _, p := range points {
+		number, index := int(p.id/cuePointTrack), int(p.id%cuePointTrack)
+		if track == nil || track.Number != number {
+			track = &Track{Number: number, DataType: DataTypeAudio}
+			file.Tracks = append(file.Tracks, track)
+		}
+		frames, err := opts.Rounding.frames(toDuration(p.offset))
+		if err != nil {
+			return nil, nil, err
+		}
+		idx := Index{Number: index, Time: TimeFromFrames(frames)}
+		track.Indexes = append(track.Indexes, idx)
+		if index == 1 {
+			track.Title, track.Performer = p.label, p.text
+			roundingErrors = append(roundingErrors, time.Duration(idx.Time.nanoseconds())-toDuration(p.offset))
+		}
+	}
+	setPositions(sheet)
+
+	return sheet, roundingErrors, nil
+}
+
+// isTrackCuePoints returns true if the points identifiers are made of
+// tracks and indexes numbers in the order of their positions.
+func isTrackCuePoints(points []cuePoint) bool {
+	prevTrack, prevIndex := 0, 0
+	for _, p := range points {
+		number, index := int(p.id/cuePointTrack), int(p.id%cuePointTrack)
+		switch {
+		case number == prevTrack && index == prevIndex+1:
+		case number == prevTrack+1 && (index == 0 || index == 1):
+		case prevTrack == 0 && number > 0 && (index == 0 || index == 1):
+		default:
+			return false
+		}
+		prevTrack, prevIndex = number, index
+	}
+	// Every track should have INDEX 01.
+	for i, p := range points {
+		if p.id%cuePointTrack == 0 && (i+1 == len(points) || points[i+1].id != p.id+1) {
+			return false
+		}
+	}
+	return true
+}
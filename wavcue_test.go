@@ -0,0 +1,164 @@
+package cue
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const waveCuesSheet = `FILE "radio.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "News"
+    PERFORMER "Anchor"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Weather"
+    INDEX 00 00:01:00
+    INDEX 01 00:02:00
+    INDEX 02 00:03:00`
+
+// testWave returns 4 seconds CD audio WAVE file followed by the chunks.
+func testWave(chunks ...[]byte) []byte {
+	data := make([]byte, 4*cdFormat.sampleRate*cdFormat.blockAlign)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	wave := append(cdFormat.header(int64(len(data))), data...)
+	for _, c := range chunks {
+		wave = append(wave, c...)
+	}
+	size := uint32(len(wave) - 8)
+	wave[4], wave[5], wave[6], wave[7] = byte(size), byte(size>>8), byte(size>>16), byte(size>>24)
+	return wave
+}
+
+func checkWaveCues(t *testing.T, wave []byte) {
+	format, err := readWaveFormat(bytes.NewReader(wave), int64(len(wave)))
+	if err != nil {
+		t.Fatalf("Failed to read WAVE format. %s", err.Error())
+	}
+	if got := wave[format.dataOffset : format.dataOffset+format.dataSize]; !bytes.Equal(got, testWave()[waveHeaderSize:]) {
+		t.Fatalf("audio data is not preserved")
+	}
+	chunks, err := readChunks(bytes.NewReader(wave), int64(len(wave)))
+	if err != nil {
+		t.Fatalf("Failed to read chunks. %s", err.Error())
+	}
+	cues := 0
+	for _, c := range chunks {
+		if ok, _ := isCueChunk(bytes.NewReader(wave), c); ok {
+			cues++
+		}
+	}
+	if cues != 2 {
+		t.Fatalf("expected cue and adtl chunks, got %d", cues)
+	}
+
+	sheet, roundingErrors, err := ReadWaveCues(bytes.NewReader(wave), int64(len(wave)), ImportOptions{File: "radio.wav"})
+	if err != nil {
+		t.Fatalf("Failed to read cues. %s", err.Error())
+	}
+	if len(roundingErrors) != 2 || roundingErrors[0] != 0 || roundingErrors[1] != 0 {
+		t.Fatalf("unexpected rounding errors %v", roundingErrors)
+	}
+	var out bytes.Buffer
+	if err := Write(&out, sheet); err != nil {
+		t.Fatalf("Failed to write sheet. %s", err.Error())
+	}
+	if got := strings.TrimSpace(out.String()); got != waveCuesSheet {
+		t.Fatalf("unexpected sheet\n%s", got)
+	}
+	if sheet.Files[0].Duration != 4 {
+		t.Fatalf("unexpected duration %f", sheet.Files[0].Duration)
+	}
+}
+
+func TestWriteWaveCues(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(waveCuesSheet))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	old := encodeCuePoints([]cuePoint{{id: 1, offset: 10, label: "Old"}})
+	var tests = [][]byte{
+		testWave(),
+		testWave(old),
+		testWave([]byte("odd \x01\x00\x00\x00x\x00"), old),
+	}
+	for _, input := range tests {
+		var out bytes.Buffer
+		if err := WriteWaveCues(&out, bytes.NewReader(input), int64(len(input)), sheet); err != nil {
+			t.Fatalf("Failed to write cues. %s", err.Error())
+		}
+		checkWaveCues(t, out.Bytes())
+	}
+
+	sheet.Files = append(sheet.Files, &File{Name: "more.wav"})
+	input := testWave()
+	if err := WriteWaveCues(new(bytes.Buffer), bytes.NewReader(input), int64(len(input)), sheet); err == nil {
+		t.Fatalf("multi-file sheet is written")
+	}
+}
+
+func TestUpdateWaveCues(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(waveCuesSheet))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	old := encodeCuePoints([]cuePoint{{id: 1, offset: 10, label: "Old", length: 5}})
+	var tests = [][]byte{
+		testWave(),
+		testWave(old),
+		// Old chunks before other chunks become JUNK.
+		testWave(old, []byte("odd \x01\x00\x00\x00x\x00")),
+	}
+	for _, input := range tests {
+		name := filepath.Join(t.TempDir(), "radio.wav")
+		if err := os.WriteFile(name, input, 0644); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.OpenFile(name, os.O_RDWR, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = UpdateWaveCues(f, sheet)
+		f.Close()
+		if err != nil {
+			t.Fatalf("Failed to update cues. %s", err.Error())
+		}
+		wave, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkWaveCues(t, wave)
+	}
+}
+
+func TestReadWaveCuesMarkers(t *testing.T) {
+	// Markers of other software become tracks.
+	samples := uint32(cdFormat.sampleRate)
+	wave := testWave(encodeCuePoints([]cuePoint{
+		{id: 7, offset: 0, label: "Intro"},
+		{id: 3, offset: samples + 100, label: "Talk", length: samples},
+	}))
+
+	sheet, roundingErrors, err := ReadWaveCues(bytes.NewReader(wave), int64(len(wave)), ImportOptions{File: "radio.wav"})
+	if err != nil {
+		t.Fatalf("Failed to read cues. %s", err.Error())
+	}
+	tracks := sheet.Files[0].Tracks
+	if len(tracks) != 2 || tracks[1].Title != "Talk" || tracks[1].Indexes[0].Time != (Time{0, 1, 0}) {
+		t.Fatalf("unexpected tracks %+v", tracks)
+	}
+	if roundingErrors[1] != -time.Duration(100)*time.Second/time.Duration(samples) {
+		t.Fatalf("unexpected rounding errors %v", roundingErrors)
+	}
+
+	if _, _, err := ReadWaveCues(bytes.NewReader(testWave()), int64(len(testWave())), ImportOptions{}); err == nil {
+		t.Fatalf("WAVE file without cues is read")
+	}
+}
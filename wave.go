@@ -0,0 +1,157 @@
+package cue
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// Size of the canonical PCM WAVE file header.
+	waveHeaderSize = 44
+	// Size of the PCM fmt chunk and the maximum fmt chunk size read.
+	waveFmtSize    = 16
+	waveFmtMaxSize = 1024
+	// PCM and extensible WAVE format tags.
+	waveFormatPCM        = 1
+	waveFormatExtensible = 0xfffe
+)
+
+// riffChunk describes location of the RIFF chunk.
+type riffChunk struct {
+	// Chunk identifier.
+	id string
+	// Offset of the chunk data.
+	offset int64
+	// Size of the chunk data.
+	size int64
+}
+
+// waveFormat describes PCM data of the WAVE file.
+type waveFormat struct {
+	channels      int
+	sampleRate    int
+	bitsPerSample int
+	blockAlign    int
+	// Data of the source fmt chunk, nil for the canonical PCM format.
+	fmtChunk []byte
+	// Location of the audio samples.
+	dataOffset int64
+	dataSize   int64
+}
+
+// cdFormat is the format of the CD audio data.
+var cdFormat = waveFormat{channels: 2, sampleRate: 44100, bitsPerSample: 16, blockAlign: 4}
+
+// readChunks returns top level chunks of the RIFF WAVE file of the given size.
+func readChunks(r io.ReaderAt, size int64) ([]riffChunk, error) {
+	buf := make([]byte, 12)
+	if _, err := r.ReadAt(buf, 0); err != nil {
+		return nil, errors.Wrap(err, "failed to read RIFF header")
+	}
+	if string(buf[:4]) != "RIFF" || string(buf[8:]) != "WAVE" {
+		return nil, errors.New("not a RIFF WAVE file")
+	}
+
+	var chunks []riffChunk
+	for offset := int64(12); offset+8 <= size; {
+		if _, err := r.ReadAt(buf[:8], offset); err != nil {
+			return nil, errors.Wrap(err, "failed to read chunk header")
+		}
+		chunk := riffChunk{
+			id:     string(buf[:4]),
+			offset: offset + 8,
+			size:   int64(binary.LittleEndian.Uint32(buf[4:8])),
+		}
+		// Truncated files are common, so the last chunk is clamped.
+		if chunk.offset+chunk.size > size {
+			chunk.size = size - chunk.offset
+		}
+		chunks = append(chunks, chunk)
+		// Chunks are word aligned.
+		offset = chunk.offset + chunk.size + chunk.size&1
+	}
+
+	return chunks, nil
+}
+
+// readWaveFormat reads PCM format and data location of the WAVE file.
+func readWaveFormat(r io.ReaderAt, size int64) (format waveFormat, err error) {
+	chunks, err := readChunks(r, size)
+	if err != nil {
+		return
+	}
+
+	var hasFormat, hasData bool
+	for _, c := range chunks {
+		switch c.id {
+		case "fmt ":
+			if c.size < waveFmtSize {
+				return format, errors.New("fmt chunk is too short")
+			}
+			if c.size > waveFmtMaxSize {
+				return format, errors.New("fmt chunk is too long")
+			}
+			buf := make([]byte, c.size)
+			if _, err = r.ReadAt(buf, c.offset); err != nil {
+				return format, errors.Wrap(err, "failed to read fmt chunk")
+			}
+			if tag := binary.LittleEndian.Uint16(buf); tag != waveFormatPCM && tag != waveFormatExtensible {
+				return format, fmt.Errorf("unsupported WAVE format 0x%x, PCM expected", tag)
+			}
+			format.channels = int(binary.LittleEndian.Uint16(buf[2:]))
+			format.sampleRate = int(binary.LittleEndian.Uint32(buf[4:]))
+			format.blockAlign = int(binary.LittleEndian.Uint16(buf[12:]))
+			format.bitsPerSample = int(binary.LittleEndian.Uint16(buf[14:]))
+			format.fmtChunk = buf
+			hasFormat = true
+		case "data":
+			format.dataOffset = c.offset
+			format.dataSize = c.size
+			hasData = true
+		}
+	}
+	if !hasFormat || !hasData {
+		return format, errors.New("WAVE file has no fmt or data chunk")
+	}
+	if format.blockAlign == 0 || format.sampleRate == 0 {
+		return format, errors.New("illegal WAVE format")
+	}
+
+	return format, nil
+}
+
+// frameOffset returns byte offset of the CD frame in the audio data.
+func (f waveFormat) frameOffset(frames int) int64 {
+	return int64(frames) * int64(f.sampleRate) / framesPerSecond * int64(f.blockAlign)
+}
+
+// header returns WAVE file header for the given data size. The source
+// fmt chunk is copied (e.g. WAVE_FORMAT_EXTENSIBLE one), canonical PCM
+// header is returned if the format has no source chunk.
+func (f waveFormat) header(dataSize int64) []byte {
+	fmtChunk := f.fmtChunk
+	if fmtChunk == nil {
+		fmtChunk = make([]byte, waveFmtSize)
+		binary.LittleEndian.PutUint16(fmtChunk, waveFormatPCM)
+		binary.LittleEndian.PutUint16(fmtChunk[2:], uint16(f.channels))
+		binary.LittleEndian.PutUint32(fmtChunk[4:], uint32(f.sampleRate))
+		binary.LittleEndian.PutUint32(fmtChunk[8:], uint32(f.sampleRate*f.blockAlign))
+		binary.LittleEndian.PutUint16(fmtChunk[12:], uint16(f.blockAlign))
+		binary.LittleEndian.PutUint16(fmtChunk[14:], uint16(f.bitsPerSample))
+	}
+	// Chunks are word aligned.
+	fmtSize := len(fmtChunk) + len(fmtChunk)&1
+
+	h := make([]byte, 20+fmtSize+8)
+	copy(h, "RIFF")
+	binary.LittleEndian.PutUint32(h[4:], uint32(int64(len(h))-8+dataSize))
+	copy(h[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(h[16:], uint32(len(fmtChunk)))
+	copy(h[20:], fmtChunk)
+	copy(h[20+fmtSize:], "data")
+	binary.LittleEndian.PutUint32(h[24+fmtSize:], uint32(dataSize))
+	return h
+}
@@ -0,0 +1,146 @@
+package cue
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Write writes the sheet in the cue-sheet format.
+// Tracks started in the previous file (gaps appended layout) are written
+// before the FILE command of their file. Sessions commands are written
+// before the first session track (and its FILE command).
+func Write(w io.Writer, sheet *Sheet) error {
+	return writeSheet(w, sheet, quote)
+}
+
+// writeSheet writes the sheet with strings parameters quoted by q.
+func writeSheet(w io.Writer, sheet *Sheet, q func(string) string) error {
+	var buf bytes.Buffer
+
+	for _, c := range sheet.Comments {
+		fmt.Fprintf(&buf, "REM %s\n", c)
+	}
+	if sheet.Catalog != "" {
+		fmt.Fprintf(&buf, "CATALOG %s\n", sheet.Catalog)
+	}
+	writeString(&buf, q, "", "CDTEXTFILE", sheet.CdTextFile)
+	writeString(&buf, q, "", "PERFORMER", sheet.Performer)
+	writeString(&buf, q, "", "TITLE", sheet.Title)
+	writeString(&buf, q, "", "SONGWRITER", sheet.Songwriter)
+
+	for fi, f := range sheet.Files {
+		// The first track which header follows the FILE command.
+		first := 0
+		if len(f.Tracks) > 0 && isContinued(f.Tracks[0]) {
+			first = 1
+		}
+		if first < len(f.Tracks) {
+			writeSession(&buf, sheet, f.Tracks[first].Number)
+		}
+
+		fmt.Fprintf(&buf, "FILE %s %s\n", q(f.Name), f.Type)
+		for ti, t := range f.Tracks {
+			if ti != 0 || !isContinued(t) {
+				if ti != first {
+					writeSession(&buf, sheet, t.Number)
+				}
+				writeTrackHeader(&buf, q, t)
+			}
+			writeIndexes(&buf, t, false)
+			if t.Postgap.TotalFrames() != 0 {
+				fmt.Fprintf(&buf, "    POSTGAP %s\n", t.Postgap)
+			}
+		}
+
+		// The next file track which is started in this file.
+		if fi+1 < len(sheet.Files) {
+			next := sheet.Files[fi+1]
+			if len(next.Tracks) > 0 && isContinued(next.Tracks[0]) {
+				writeSession(&buf, sheet, next.Tracks[0].Number)
+				writeTrackHeader(&buf, q, next.Tracks[0])
+				writeIndexes(&buf, next.Tracks[0], true)
+			}
+		}
+	}
+
+	if l := len(sheet.Sessions); l > 0 && sheet.Sessions[l-1].LeadOut.TotalFrames() != 0 {
+		fmt.Fprintf(&buf, "REM LEAD-OUT %s\n", sheet.Sessions[l-1].LeadOut)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeSession writes REM SESSION command (with the previous session
+// lead-out and the session lead-in) if the track starts a session.
+func writeSession(buf *bytes.Buffer, sheet *Sheet, number int) {
+	s := sheet.isSessionStart(number)
+	if s == nil {
+		return
+	}
+	if s.Number > 1 {
+		if prev := sheet.Sessions[s.Number-2]; prev.LeadOut.TotalFrames() != 0 {
+			fmt.Fprintf(buf, "REM LEAD-OUT %s\n", prev.LeadOut)
+		}
+	}
+	fmt.Fprintf(buf, "REM SESSION %02d\n", s.Number)
+	if s.LeadIn.TotalFrames() != 0 {
+		fmt.Fprintf(buf, "REM LEAD-IN %s\n", s.LeadIn)
+	}
+}
+
+// isContinued returns true if the track is started in the previous file.
+func isContinued(t *Track) bool {
+	return len(t.Indexes) > 0 && t.Indexes[0].InPreviousFile
+}
+
+// writeTrackHeader writes TRACK command and all track commands which
+// should appear before INDEX commands.
+func writeTrackHeader(buf *bytes.Buffer, q func(string) string, t *Track) {
+	fmt.Fprintf(buf, "  TRACK %02d %s\n", t.Number, t.DataType)
+	writeString(buf, q, "    ", "TITLE", t.Title)
+	writeString(buf, q, "    ", "PERFORMER", t.Performer)
+	writeString(buf, q, "    ", "SONGWRITER", t.Songwriter)
+	if len(t.Flags) > 0 {
+		flags := make([]string, len(t.Flags))
+		for i, f := range t.Flags {
+			flags[i] = f.String()
+		}
+		fmt.Fprintf(buf, "    FLAGS %s\n", strings.Join(flags, " "))
+	}
+	if t.Isrc != "" {
+		fmt.Fprintf(buf, "    ISRC %s\n", t.Isrc)
+	}
+	if t.Pregap.TotalFrames() != 0 {
+		fmt.Fprintf(buf, "    PREGAP %s\n", t.Pregap)
+	}
+	for _, c := range t.Comments {
+		fmt.Fprintf(buf, "    REM %s\n", c)
+	}
+}
+
+// writeIndexes writes track INDEX commands located in the previous
+// file or in the current file.
+func writeIndexes(buf *bytes.Buffer, t *Track, inPreviousFile bool) {
+	for _, idx := range t.Indexes {
+		if idx.InPreviousFile == inPreviousFile {
+			fmt.Fprintf(buf, "    INDEX %02d %s\n", idx.Number, idx.Time)
+		}
+	}
+}
+
+// writeString writes command with string parameter quoted by q if it's not empty.
+func writeString(buf *bytes.Buffer, q func(string) string, indent, cmd, value string) {
+	if value != "" {
+		fmt.Fprintf(buf, "%s%s %s\n", indent, cmd, q(value))
+	}
+}
+
+// quote returns string parameter wrapped with quotes. Quotes, backslashes
+// and control characters are escaped in the way parseCommand expects.
+func quote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+	return `"` + r.Replace(s) + `"`
+}
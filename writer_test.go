@@ -0,0 +1,51 @@
+package cue
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	data, err := ioutil.ReadFile("test.cue")
+	if err != nil {
+		t.Fatalf("Failed to read file. %s", err.Error())
+	}
+
+	for _, input := range []string{string(data), layoutAppended, layoutImage} {
+		sheet, err := Parse(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("Failed to parse sheet. %s", err.Error())
+		}
+		sheet.Files[0].Tracks[0].Title = `Say "Hello\World"`
+		sheet.Files[0].Tracks[0].Flags = []TrackFlag{TrackFlagDcp, TrackFlagPre}
+		sheet.Files[0].Tracks[0].Pregap = Time{0, 2, 0}
+
+		var buf bytes.Buffer
+		if err := Write(&buf, sheet); err != nil {
+			t.Fatalf("Failed to write sheet. %s", err.Error())
+		}
+		written, err := Parse(&buf)
+		if err != nil {
+			t.Fatalf("Failed to parse written sheet. %s", err.Error())
+		}
+		if !reflect.DeepEqual(sheet, written) {
+			t.Fatalf("written sheet differs from the original one")
+		}
+	}
+}
+
+func TestWriteGapsAppended(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(layoutAppended))
+	if err != nil {
+		t.Fatalf("Failed to parse sheet. %s", err.Error())
+	}
+
+	var buf bytes.Buffer
+	Write(&buf, sheet)
+	if buf.String() != layoutAppended {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}